@@ -0,0 +1,177 @@
+package p24
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AuditEntry is a record of p24 api call with verified response signature
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Endpoint Endpoint  `json:"endpoint"`
+	URL      string    `json:"url"`
+	// Request is redacted http request body
+	Request string `json:"request"`
+	// Data is the raw signed content of response '<data>' tag, it is not redacted
+	// to allow signature verification
+	Data []byte `json:"data"`
+	// MerchantID and Signature are response MerchantSign
+	MerchantID string `json:"merchant_id"`
+	Signature  string `json:"signature"`
+	// PrevHash is Hash of the previous entry or empty string for the first one
+	PrevHash string `json:"prev_hash"`
+	// Hash is hex encoded sha256 hash of PrevHash and the entry, see AuditEntry.ComputeHash
+	Hash string `json:"hash"`
+	// Seq is a number of the entry in audit log starting from 1
+	Seq uint64 `json:"seq"`
+}
+
+// ComputeHash returns hash of e that links it with the previous entry.
+// It is sha256 of PrevHash and json representation of e with empty Hash
+func (e AuditEntry) ComputeHash() (string, error) {
+	e.Hash = ""
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", errors.Wrap(err, "can`t marshal audit entry")
+	}
+	h := sha256.New()
+	_, _ = io.WriteString(h, e.PrevHash)
+	_, _ = h.Write(b)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// AuditSink receives audit entries of p24 api calls with verified responses.
+// Client call fails if Append returns an error
+type AuditSink interface {
+	Append(ctx context.Context, entry AuditEntry) error
+}
+
+// AuditLog is an AuditSink that writes hash chained entries to io.Writer as json lines.
+// It sets Seq, PrevHash and Hash of appended entries. It is safe for concurrent use.
+//
+// The hash chain is not keyed, so anyone able to write the log can modify entries and recompute
+// hashes of the following ones or truncate the log. Only AuditSummary kept apart from the log
+// as an anchor protects the chain, see VerifyAuditLog
+type AuditLog struct {
+	w        io.Writer
+	lastHash string
+	seq      uint64
+	mu       sync.Mutex
+}
+
+// NewAuditLog returns AuditLog writing to w.
+// Use AuditSummary of existing log to continue its hash chain or zero value for a new log
+func NewAuditLog(w io.Writer, prev AuditSummary) *AuditLog {
+	return &AuditLog{w: w, lastHash: prev.LastHash, seq: uint64(prev.Entries)}
+}
+
+// Append implements AuditSink interface for l
+func (l *AuditLog) Append(_ context.Context, entry AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.Seq, entry.PrevHash = l.seq+1, l.lastHash
+	hash, err := entry.ComputeHash()
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "can`t marshal audit entry")
+	}
+	if _, err := l.w.Write(append(b, '\n')); err != nil {
+		return errors.Wrap(err, "can`t write audit entry")
+	}
+	l.seq, l.lastHash = entry.Seq, entry.Hash
+	return nil
+}
+
+// AuditSummary describes verified audit log
+type AuditSummary struct {
+	// LastHash is Hash of the last entry
+	LastHash string
+	// Entries is a number of entries
+	Entries int
+}
+
+// VerifyAuditLog reads json lines audit log from r and checks its hash chain
+// and signatures of all entries with s. Signer must sign with the merchant password
+// that was actual when entries were written. Lines are not limited in length.
+// It returns an error describing the first invalid entry.
+//
+// A valid chain only proves that entries were not changed after the first one,
+// a recomputed or truncated chain is detected only by comparison of the returned summary
+// with AuditSummary saved before
+func VerifyAuditLog(ctx context.Context, r io.Reader, s Signer) (AuditSummary, error) {
+	var summary AuditSummary
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		b, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return summary, errors.Wrap(err, "can`t read audit log")
+		}
+		if b = bytes.TrimRight(b, "\r\n"); len(b) > 0 {
+			var entry AuditEntry
+			if err := json.Unmarshal(b, &entry); err != nil {
+				return summary, errors.Wrapf(err, "line %d: can`t unmarshal audit entry", line)
+			}
+			if err := verifyAuditEntry(ctx, entry, summary, s); err != nil {
+				return summary, errors.Wrapf(err, "line %d", line)
+			}
+			summary.LastHash = entry.Hash
+			summary.Entries++
+		}
+		if err == io.EOF {
+			return summary, nil
+		}
+	}
+}
+
+// verifyAuditEntry checks entry with summary of previous entries
+func verifyAuditEntry(ctx context.Context, entry AuditEntry, prev AuditSummary, s Signer) error {
+	if entry.Seq != uint64(prev.Entries)+1 {
+		return errors.Errorf("unexpected seq %d, expected %d", entry.Seq, prev.Entries+1)
+	}
+	if entry.PrevHash != prev.LastHash {
+		return errors.New("broken hash chain: prev hash doesn`t match hash of previous entry")
+	}
+	hash, err := entry.ComputeHash()
+	if err != nil {
+		return err
+	}
+	if hash != entry.Hash {
+		return errors.New("entry hash mismatch: entry is modified")
+	}
+	if err := VerifySignature(ctx, s, entry.Data, MerchantSign{ID: entry.MerchantID, Sign: entry.Signature}); err != nil {
+		return errors.Wrap(err, "data signature")
+	}
+	return nil
+}
+
+// appendAudit appends verified call to Client audit sink if it is set
+func (c *Client) appendAudit(ctx context.Context, call Call, httpReqBody, data []byte, sign MerchantSign) error {
+	if c.audit == nil {
+		return nil
+	}
+	err := c.audit.Append(ctx, AuditEntry{
+		Time:       time.Now().UTC(),
+		Endpoint:   call.Endpoint,
+		URL:        call.URL,
+		Request:    c.redact.Redact(string(httpReqBody)),
+		Data:       append([]byte(nil), data...),
+		MerchantID: sign.ID,
+		Signature:  sign.Sign,
+	})
+	return errors.Wrap(err, "can`t append audit entry")
+}
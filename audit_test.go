@@ -0,0 +1,159 @@
+package p24
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Audit(t *testing.T) {
+	m := Merchant{"id", "pass"}
+	data := `<oper>cmt</oper><info><cardbalance><card><card_number>1234567890123456</card_number></card><bal_date>01.01.21 10:00</bal_date></cardbalance></info>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(signedResponse(m, "id", data)))
+	}))
+	defer srv.Close()
+
+	buf := &bytes.Buffer{}
+	newClient := func(audit AuditSink) *Client {
+		cli, err := NewClient(ClientOpts{HTTP: http.DefaultClient, BaseURL: srv.URL + "/", Merchant: m, Audit: audit})
+		require.NoError(t, err)
+		return cli
+	}
+	getBalance := func(cli *Client) error {
+		_, err := cli.GetCardBalance(context.Background(), BalanceOpts{CardNumber: "1234567890123456"})
+		return err
+	}
+
+	cli := newClient(NewAuditLog(buf, AuditSummary{}))
+	require.NoError(t, getBalance(cli))
+	require.NoError(t, getBalance(cli))
+
+	summary, err := VerifyAuditLog(context.Background(), bytes.NewReader(buf.Bytes()), m)
+	require.NoError(t, err)
+	require.Equal(t, 2, summary.Entries)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var entry AuditEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	require.Equal(t, uint64(2), entry.Seq)
+	require.Equal(t, summary.LastHash, entry.Hash)
+	require.Equal(t, EndpointCardBalance, entry.Endpoint)
+	require.Equal(t, data, string(entry.Data))
+	require.Equal(t, m.Sign([]byte(data)), MerchantSign{entry.MerchantID, entry.Signature})
+	require.NotContains(t, entry.Request, "1234567890123456")
+	require.Contains(t, entry.Request, "123456******3456")
+
+	// the chain is continued by a new AuditLog
+	require.NoError(t, getBalance(newClient(NewAuditLog(buf, summary))))
+	summary, err = VerifyAuditLog(context.Background(), bytes.NewReader(buf.Bytes()), m)
+	require.NoError(t, err)
+	require.Equal(t, 3, summary.Entries)
+
+	// audit failure fails the call
+	err = getBalance(newClient(NewAuditLog(errWriter{}, AuditSummary{})))
+	require.EqualError(t, err, "can`t append audit entry: can`t write audit entry: write failed")
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, errors.New("write failed") }
+
+func TestVerifyAuditLog(t *testing.T) {
+	m := Merchant{"id", "pass"}
+	buf := &bytes.Buffer{}
+	log := NewAuditLog(buf, AuditSummary{})
+	for i := 0; i < 3; i++ {
+		data := []byte("<oper>cmt</oper><info>" + strconv.Itoa(i) + "</info>")
+		sign := m.Sign(data)
+		require.NoError(t, log.Append(context.Background(), AuditEntry{Data: data, MerchantID: sign.ID, Signature: sign.Sign}))
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	modify := func(line string, f func(e *AuditEntry)) string {
+		var e AuditEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		f(&e)
+		b, err := json.Marshal(e)
+		require.NoError(t, err)
+		return string(b)
+	}
+	rehash := func(e *AuditEntry) {
+		var err error
+		e.Hash, err = e.ComputeHash()
+		require.NoError(t, err)
+	}
+
+	cases := []struct {
+		signer Signer
+		errMsg string
+		lines  []string
+	}{
+		{lines: lines},
+		{lines: []string{lines[0] + "\r", "", lines[1] + "\r", lines[2] + "\n"}},
+		{lines: []string{lines[0], lines[2]}, errMsg: "line 2: unexpected seq 3, expected 2"},
+		{lines: []string{lines[1], lines[2]}, errMsg: "line 1: unexpected seq 2, expected 1"},
+		{
+			lines:  []string{lines[0], modify(lines[1], func(e *AuditEntry) { e.Data = []byte("<info>9</info>") }), lines[2]},
+			errMsg: "line 2: entry hash mismatch: entry is modified",
+		},
+		{
+			lines: []string{lines[0], modify(lines[1], func(e *AuditEntry) {
+				e.Data = []byte("<info>9</info>")
+				rehash(e)
+			}), lines[2]},
+			errMsg: "line 2: data signature: invalid signature",
+		},
+		{
+			lines: []string{lines[0], modify(lines[1], func(e *AuditEntry) {
+				e.PrevHash = ""
+				rehash(e)
+			}), lines[2]},
+			errMsg: "line 2: broken hash chain: prev hash doesn`t match hash of previous entry",
+		},
+		{lines: lines, signer: Merchant{"id", "other pass"}, errMsg: "line 1: data signature: invalid signature"},
+		{lines: []string{lines[0], "{"}, errMsg: "line 2: can`t unmarshal audit entry: unexpected end of JSON input"},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			signer := c.signer
+			if signer == nil {
+				signer = m
+			}
+			summary, err := VerifyAuditLog(context.Background(), strings.NewReader(strings.Join(c.lines, "\n")), signer)
+			if c.errMsg != "" {
+				require.EqualError(t, err, c.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			var last AuditEntry
+			require.NoError(t, json.Unmarshal([]byte(lines[2]), &last))
+			require.Equal(t, AuditSummary{LastHash: last.Hash, Entries: 3}, summary)
+		})
+	}
+}
+
+func TestVerifyAuditLog_LongLine(t *testing.T) {
+	m := Merchant{"id", "pass"}
+	buf := &bytes.Buffer{}
+	// base64 encoded data makes the line longer than twice of DefaultMaxResponseBytes
+	data := bytes.Repeat([]byte("a"), int(DefaultMaxResponseBytes)*3/2)
+	sign := m.Sign(data)
+	require.NoError(t, NewAuditLog(buf, AuditSummary{}).Append(context.Background(), AuditEntry{Data: data, MerchantID: sign.ID, Signature: sign.Sign}))
+	require.Greater(t, buf.Len(), int(DefaultMaxResponseBytes)*2)
+
+	summary, err := VerifyAuditLog(context.Background(), buf, m)
+	require.NoError(t, err)
+	require.Equal(t, 1, summary.Entries)
+}
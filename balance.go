@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/xml"
 	"fmt"
-	"net/http"
 	"strings"
 	"time"
 
@@ -12,7 +11,6 @@ import (
 )
 
 const (
-	cardBalanceAPIURL         = "https://api.privatbank.ua/p24api/balance"
 	cardBalanceRespDateLayout = "02.01.06"
 	cardBalanceRespTimeLayout = "15:04"
 )
@@ -128,8 +126,16 @@ func (c *Client) GetCardBalance(ctx context.Context, opts BalanceOpts) (CardBala
 	type info struct {
 		CardBalance CardBalance `xml:"cardbalance"`
 	}
+	requested := c.clock()
+	check := func(resp Response) error {
+		balance := resp.Data.Info.(info).CardBalance
+		if err := checkCard(opts.CardNumber, balance.Card.Number); err != nil {
+			return err
+		}
+		return c.checkFresh(requested, balance.Date, cardBalanceRespDateLayout+" "+cardBalanceRespTimeLayout)
+	}
 	resp := Response{Data: ResponseData{Info: info{}}}
-	if err := c.DoContext(ctx, cardBalanceAPIURL, http.MethodPost, NewRequest(c.merchant, reqData), &resp); err != nil {
+	if err := c.do(ctx, SpanGetCardBalance, EndpointCardBalance, reqData, check, nil, &resp); err != nil {
 		return CardBalance{}, err
 	}
 
@@ -120,7 +120,7 @@ func TestClient_GetCardBalance(t *testing.T) {
 				return tr.Result(), nil
 			}
 
-			cli := Client{do, nil, m}
+			cli := Client{http: do, merchant: m}
 			actual, err := cli.GetCardBalance(context.Background(), c.opts)
 			if err != nil {
 				require.NotEmpty(t, c.errMsg)
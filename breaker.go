@@ -0,0 +1,220 @@
+package p24
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCircuitOpen is returned by Client while circuit breaker of called endpoint is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is a state of circuit breaker
+type BreakerState int
+
+// Circuit breaker states
+const (
+	// BreakerClosed state passes all calls and counts their failures
+	BreakerClosed BreakerState = iota
+	// BreakerOpen state rejects all calls with ErrCircuitOpen until cooldown is passed
+	BreakerOpen
+	// BreakerHalfOpen state passes a limited number of trial calls
+	BreakerHalfOpen
+)
+
+// String returns name of s
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// BreakerOpts is a full set of all parameters to initialize circuit breakers of Client endpoints
+type BreakerOpts struct {
+	// OnStateChange is called on every state change of endpoint circuit breaker
+	OnStateChange func(endpoint Endpoint, from, to BreakerState)
+
+	// IsFailure reports whether err counts as endpoint failure.
+	// Retryable errors and context deadline are failures if nil
+	IsFailure func(err error) bool
+
+	// FailureRatio is a ratio of failed calls in closed state that opens the breaker.
+	// 0.5 is used if zero
+	FailureRatio float64
+
+	// MinRequests is a min number of calls in closed state before FailureRatio is checked.
+	// 5 is used if zero
+	MinRequests int
+
+	// Interval is a period of clearing closed state counts.
+	// Counts are never cleared while the breaker is closed if zero
+	Interval time.Duration
+
+	// Cooldown is a duration of open state. 30 seconds is used if zero
+	Cooldown time.Duration
+
+	// HalfOpenMaxRequests is a number of trial calls in half-open state,
+	// the breaker is closed when all of them succeed. 1 is used if zero
+	HalfOpenMaxRequests int
+}
+
+// circuitBreaker is a circuit breaker of a p24 endpoint
+type circuitBreaker struct {
+	expiry     time.Time
+	now        func() time.Time
+	opts       BreakerOpts
+	endpoint   Endpoint
+	generation uint64
+	requests   int
+	failures   int
+	successes  int
+	state      BreakerState
+	// notifications are state change callbacks to be called after b.mu is released
+	notifications []func()
+	mu            sync.Mutex
+}
+
+func newCircuitBreaker(endpoint Endpoint, opts BreakerOpts) *circuitBreaker {
+	if opts.FailureRatio <= 0 {
+		opts.FailureRatio = 0.5
+	}
+	if opts.MinRequests <= 0 {
+		opts.MinRequests = 5
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = 30 * time.Second
+	}
+	if opts.HalfOpenMaxRequests <= 0 {
+		opts.HalfOpenMaxRequests = 1
+	}
+	if opts.IsFailure == nil {
+		opts.IsFailure = func(err error) bool {
+			return IsRetryable(err) || errors.Is(err, context.DeadlineExceeded)
+		}
+	}
+
+	b := &circuitBreaker{endpoint: endpoint, opts: opts, now: time.Now}
+	b.toState(BreakerClosed, b.now())
+	return b
+}
+
+// State returns current state of b
+func (b *circuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.unlock()
+	b.refresh(b.now())
+	return b.state
+}
+
+// allow returns generation of a call if it is allowed or ErrCircuitOpen otherwise
+func (b *circuitBreaker) allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	b.refresh(b.now())
+	switch {
+	case b.state == BreakerOpen:
+		return 0, ErrCircuitOpen
+	case b.state == BreakerHalfOpen && b.requests >= b.opts.HalfOpenMaxRequests:
+		return 0, ErrCircuitOpen
+	}
+	b.requests++
+	return b.generation, nil
+}
+
+// done reports result of a call with given generation
+func (b *circuitBreaker) done(generation uint64, err error) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	now := b.now()
+	b.refresh(now)
+	if generation != b.generation {
+		return
+	}
+
+	if err != nil && b.opts.IsFailure(err) {
+		b.failures++
+		switch {
+		case b.state == BreakerHalfOpen:
+			b.toState(BreakerOpen, now)
+		case b.requests >= b.opts.MinRequests && float64(b.failures)/float64(b.requests) >= b.opts.FailureRatio:
+			b.toState(BreakerOpen, now)
+		}
+		return
+	}
+
+	b.successes++
+	if b.state == BreakerHalfOpen && b.successes >= b.opts.HalfOpenMaxRequests {
+		b.toState(BreakerClosed, now)
+	}
+}
+
+// refresh moves b to the next generation if current one is expired. b.mu must be held
+func (b *circuitBreaker) refresh(now time.Time) {
+	if b.expiry.IsZero() || now.Before(b.expiry) {
+		return
+	}
+	switch b.state {
+	case BreakerClosed:
+		b.newGeneration(now)
+	case BreakerOpen:
+		b.toState(BreakerHalfOpen, now)
+	}
+}
+
+// toState moves b to the state. b.mu must be held
+func (b *circuitBreaker) toState(state BreakerState, now time.Time) {
+	prev := b.state
+	b.state = state
+	b.newGeneration(now)
+	if prev != state && b.opts.OnStateChange != nil {
+		onStateChange, endpoint := b.opts.OnStateChange, b.endpoint
+		b.notifications = append(b.notifications, func() { onStateChange(endpoint, prev, state) })
+	}
+}
+
+// unlock releases b.mu and calls pending state change callbacks
+func (b *circuitBreaker) unlock() {
+	notifications := b.notifications
+	b.notifications = nil
+	b.mu.Unlock()
+	for _, notify := range notifications {
+		notify()
+	}
+}
+
+// newGeneration clears counts of b and sets expiry of current state. b.mu must be held
+func (b *circuitBreaker) newGeneration(now time.Time) {
+	b.generation++
+	b.requests, b.failures, b.successes = 0, 0, 0
+
+	b.expiry = time.Time{}
+	switch b.state {
+	case BreakerClosed:
+		if b.opts.Interval > 0 {
+			b.expiry = now.Add(b.opts.Interval)
+		}
+	case BreakerOpen:
+		b.expiry = now.Add(b.opts.Cooldown)
+	}
+}
+
+// BreakerState returns state of endpoint circuit breaker.
+// It returns BreakerClosed if Client has no circuit breakers
+func (c *Client) BreakerState(endpoint Endpoint) BreakerState {
+	if b, ok := c.breakers[endpoint]; ok {
+		return b.State()
+	}
+	return BreakerClosed
+}
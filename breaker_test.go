@@ -0,0 +1,158 @@
+package p24
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_circuitBreaker(t *testing.T) {
+	type change struct{ from, to BreakerState }
+	var changes []change
+	b := newCircuitBreaker(EndpointStatements, BreakerOpts{
+		FailureRatio:        0.5,
+		MinRequests:         4,
+		Interval:            time.Minute,
+		Cooldown:            10 * time.Second,
+		HalfOpenMaxRequests: 2,
+		OnStateChange: func(endpoint Endpoint, from, to BreakerState) {
+			require.Equal(t, EndpointStatements, endpoint)
+			changes = append(changes, change{from, to})
+		},
+	})
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+	b.newGeneration(now)
+
+	failure := &transportError{errors.New("connection reset")}
+	call := func(err error) error {
+		generation, allowErr := b.allow()
+		if allowErr != nil {
+			return allowErr
+		}
+		b.done(generation, err)
+		return nil
+	}
+
+	// non failure errors and successes keep breaker closed
+	require.NoError(t, call(nil))
+	require.NoError(t, call(errors.New("invalid signature")))
+	require.NoError(t, call(failure))
+	require.Equal(t, BreakerClosed, b.State())
+
+	// counts are cleared after interval
+	now = now.Add(time.Minute)
+	require.NoError(t, call(failure))
+	require.NoError(t, call(failure))
+	require.NoError(t, call(nil))
+	require.Equal(t, BreakerClosed, b.State())
+	require.NoError(t, call(nil))
+	require.Equal(t, BreakerClosed, b.State())
+	require.NoError(t, call(failure))
+	require.Equal(t, BreakerOpen, b.State())
+	require.ErrorIs(t, call(nil), ErrCircuitOpen)
+
+	// half open allows limited trial calls
+	now = now.Add(10 * time.Second)
+	require.Equal(t, BreakerHalfOpen, b.State())
+	g1, err := b.allow()
+	require.NoError(t, err)
+	g2, err := b.allow()
+	require.NoError(t, err)
+	_, err = b.allow()
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	b.done(g1, nil)
+	require.Equal(t, BreakerHalfOpen, b.State())
+	b.done(g2, context.DeadlineExceeded)
+	require.Equal(t, BreakerOpen, b.State())
+
+	now = now.Add(10 * time.Second)
+	require.NoError(t, call(nil))
+	require.NoError(t, call(nil))
+	require.Equal(t, BreakerClosed, b.State())
+
+	// results of previous generation are ignored
+	g, err := b.allow()
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	b.done(g, failure)
+	require.Equal(t, 0, b.failures)
+
+	require.Equal(t, []change{
+		{BreakerClosed, BreakerOpen},
+		{BreakerOpen, BreakerHalfOpen},
+		{BreakerHalfOpen, BreakerOpen},
+		{BreakerOpen, BreakerHalfOpen},
+		{BreakerHalfOpen, BreakerClosed},
+	}, changes)
+	require.Equal(t, "half-open", BreakerHalfOpen.String())
+	require.Equal(t, "BreakerState(5)", BreakerState(5).String())
+}
+
+func TestClient_DoContext_Breaker(t *testing.T) {
+	httpCalls := 0
+	cli, err := NewClient(ClientOpts{
+		HTTP: DoFunc(func(req *http.Request) (*http.Response, error) {
+			httpCalls++
+			tr := httptest.NewRecorder()
+			tr.Code = http.StatusBadGateway
+			return tr.Result(), nil
+		}),
+		Retry:   RetryPolicy{MaxAttempts: 5},
+		Breaker: &BreakerOpts{MinRequests: 2, Cooldown: time.Hour},
+	})
+	require.NoError(t, err)
+
+	url := cli.endpointURL(EndpointStatements)
+	err = cli.DoContext(context.Background(), url, http.MethodPost, Request{}, &Response{})
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.EqualError(t, err, "statements endpoint: circuit breaker is open")
+	require.Equal(t, 2, httpCalls)
+	require.Equal(t, BreakerOpen, cli.BreakerState(EndpointStatements))
+	require.Equal(t, BreakerClosed, cli.BreakerState(EndpointCardBalance))
+
+	err = cli.DoContext(context.Background(), url, http.MethodPost, Request{}, &Response{})
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.Equal(t, 2, httpCalls)
+
+	require.Equal(t, BreakerClosed, (&Client{}).BreakerState(EndpointStatements))
+}
+
+func TestClient_DoContext_BreakerLimiter(t *testing.T) {
+	httpCalls := 0
+	limiter := NewLimiter(LimiterOpts{})
+	cli, err := NewClient(ClientOpts{
+		Merchant: Merchant{"id", "pass"},
+		Limiter:  limiter,
+		HTTP: DoFunc(func(req *http.Request) (*http.Response, error) {
+			httpCalls++
+			tr := httptest.NewRecorder()
+			tr.Code = http.StatusOK
+			return tr.Result(), nil
+		}),
+		Breaker: &BreakerOpts{MinRequests: 1, Cooldown: time.Hour},
+	})
+	require.NoError(t, err)
+
+	// a slow call of the merchant holds its turn, so other calls time out in the queue
+	release, err := limiter.Acquire(context.Background(), "id")
+	require.NoError(t, err)
+	url := cli.endpointURL(EndpointStatements)
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		err = cli.DoContext(ctx, url, http.MethodPost, Request{}, &Response{})
+		cancel()
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, BreakerClosed, cli.BreakerState(EndpointStatements))
+	}
+	release()
+
+	err = cli.DoContext(context.Background(), url, http.MethodPost, Request{}, &Response{})
+	require.ErrorIs(t, err, ErrInvalidContent)
+	require.Equal(t, 1, httpCalls)
+}
@@ -7,106 +7,495 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
-// Error reports an error and the Method, URL, response/request body that caused it
-type Error struct {
-	Err         error
-	URL, Method string
-	Req, Resp   []byte
-}
-
-func (e *Error) Error() (err string) {
-	// nolint:gocritic // e.Err can be nil
-	return fmt.Sprint(e.Err)
-}
-
-func (e *Error) Unwrap() error { return e.Err }
-
-func (e *Error) Cause() error { return e.Err }
-
-func newError(err error, url, method string, resp, req []byte) *Error {
-	return &Error{err, url, method, resp, req}
-}
-
 // Client performs p24 api calls with given Doer, Merchant, Logger.
 // Implements p24 information API client.
 // see: https://api.privatbank.ua/#p24/main
 type Client struct {
 	http     Doer
-	log      Logger
+	log      StructuredLogger
 	merchant Merchant
+	sign     Signer
+	urls     map[Endpoint]string
+	retry    RetryPolicy
+	limiter  *Limiter
+	mws      []Middleware
+	maxBytes int64
+	limits   xmlLimits
+	metrics  Metrics
+	trace    Tracer
+	breakers map[Endpoint]*circuitBreaker
+	redact   Redaction
+
+	signDiagnostics bool
+	strict          bool
+	maxAge          time.Duration
+	now             func() time.Time
+	audit           AuditSink
 }
 
 // ClientOpts is a full set of all parameters to initialize Client
 type ClientOpts struct {
-	HTTP     Doer
-	Log      Logger
+	// HTTP performs http requests.
+	// Http client of NewHTTPClient with HTTPOpts is used if nil
+	HTTP Doer
+
+	// HTTPOpts are options of http client used if HTTP is nil
+	HTTPOpts HTTPClientOpts
+
+	// Log receives client events of LevelInfo and above.
+	// It is ignored if StructuredLog is set
+	Log Logger
+
+	// StructuredLog receives client events with key/value fields.
+	// Card numbers and signatures are redacted from field values
+	StructuredLog StructuredLogger
+
 	Merchant Merchant
+
+	// BaseURL is an absolute url all endpoints are resolved against.
+	// DefaultBaseURL is used if empty
+	BaseURL string
+
+	// URLs overrides urls of particular endpoints.
+	// A value can be an absolute url or a path relative to BaseURL.
+	// A leading "/" of a path doesn`t make it relative to the host: "/balance" and "balance"
+	// are both resolved to BaseURL + "balance", use an absolute url to override the whole url
+	URLs map[Endpoint]string
+
+	// Retry defines how failed api calls are retried.
+	// Zero value performs calls without retries
+	Retry RetryPolicy
+
+	// Limiter throttles and serializes requests per merchant.
+	// Requests are not limited if nil
+	Limiter *Limiter
+
+	// Middlewares wrap every api call. The first Middleware is the outermost one
+	Middlewares []Middleware
+
+	// MaxResponseBytes is a max size of http response body.
+	// DefaultMaxResponseBytes is used if zero, the size is unlimited if negative
+	MaxResponseBytes int64
+
+	// MaxXMLDepth is a max nesting depth of xml response.
+	// DefaultMaxXMLDepth is used if zero, the depth is unlimited if negative
+	MaxXMLDepth int
+
+	// MaxXMLElements is a max number of elements in xml response.
+	// DefaultMaxXMLElements is used if zero, the number is unlimited if negative
+	MaxXMLElements int
+
+	// Metrics collects metrics of api calls. Metrics are not collected if nil
+	Metrics Metrics
+
+	// Tracer traces api calls and their phases. Calls are not traced if nil
+	Tracer Tracer
+
+	// Breaker enables a circuit breaker per endpoint.
+	// Calls are not guarded by circuit breakers if nil
+	Breaker *BreakerOpts
+
+	// Redaction configures masking of card numbers, signatures, merchant ids and amounts
+	// in *Error and log events. Zero value masks all of them
+	Redaction Redaction
+
+	// SignDiagnostics makes Client return *SignatureError with SignDiagnostics
+	// of responses with invalid signature. It exposes raw signed data in errors
+	SignDiagnostics bool
+
+	// Signer signs requests and data of responses to verify their signatures instead of Merchant.
+	// Use NewCredentialsSigner to sign with credentials of CredentialsProvider
+	Signer Signer
+
+	// StrictVerify enables cross-checks of verified responses with their requests:
+	// merchant id, oper, card number and statements date range.
+	// Mismatched responses are rejected with *MismatchError
+	StrictVerify bool
+
+	// MaxResponseAge is a max age of card balance date relative to the request time
+	// checked by strict verification, older responses are rejected as replayed.
+	// DefaultMaxResponseAge is used if zero, the age is unchecked if negative
+	MaxResponseAge time.Duration
+
+	// Audit receives entries of calls with verified responses, see AuditLog.
+	// Calls are not audited if nil
+	Audit AuditSink
 }
 
-// NewClient returns Client instance with given opts
-func NewClient(opts ClientOpts) *Client {
-	var log Logger = LogFunc(func(format string, args ...interface{}) {})
-	if opts.Log != nil {
-		log = opts.Log
+// NewClient returns Client instance with given opts.
+// It returns an error if BaseURL, URLs or HTTPOpts are invalid.
+//
+// NewClient returned *Client without an error before, use MustNewClient
+// to keep single value initialization of Client with valid opts
+func NewClient(opts ClientOpts) (*Client, error) {
+	if opts.HTTP == nil {
+		httpClient, err := NewHTTPClient(opts.HTTPOpts)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid client opts")
+		}
+		opts.HTTP = httpClient
+	}
+
+	log := nopLogger
+	switch {
+	case opts.StructuredLog != nil:
+		log = opts.StructuredLog
+	case opts.Log != nil:
+		log = LogfAdapter{Logger: opts.Log, MinLevel: LevelInfo}
+	}
+	urls, err := resolveEndpointURLs(opts.BaseURL, opts.URLs)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid client opts")
+	}
+	var breakers map[Endpoint]*circuitBreaker
+	if opts.Breaker != nil {
+		breakers = make(map[Endpoint]*circuitBreaker, len(urls))
+		for endpoint := range urls {
+			breakers[endpoint] = newCircuitBreaker(endpoint, *opts.Breaker)
+		}
 	}
 	return &Client{
 		http:     opts.HTTP,
 		log:      log,
 		merchant: opts.Merchant,
+		sign:     opts.Signer,
+		urls:     urls,
+		retry:    opts.Retry,
+		limiter:  opts.Limiter,
+		mws:      opts.Middlewares,
+		maxBytes: orDefault(opts.MaxResponseBytes, DefaultMaxResponseBytes),
+		limits: xmlLimits{
+			maxDepth:    int(orDefault(int64(opts.MaxXMLDepth), DefaultMaxXMLDepth)),
+			maxElements: int(orDefault(int64(opts.MaxXMLElements), DefaultMaxXMLElements)),
+		},
+		metrics:  opts.Metrics,
+		trace:    opts.Tracer,
+		breakers: breakers,
+		redact:   opts.Redaction,
+
+		signDiagnostics: opts.SignDiagnostics,
+		strict:          opts.StrictVerify,
+		maxAge:          time.Duration(orDefault(int64(opts.MaxResponseAge), int64(DefaultMaxResponseAge))),
+		audit:           opts.Audit,
+	}, nil
+}
+
+// MustNewClient is like NewClient but panics if opts are invalid.
+// It simplifies initialization of Client with static opts
+func MustNewClient(opts ClientOpts) *Client {
+	c, err := NewClient(opts)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// clock returns current time of Client clock or time.Now if it is nil
+func (c *Client) clock() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+// orDefault returns def if v is zero
+func orDefault(v, def int64) int64 {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// signer returns Client Signer or Client Merchant if it is nil
+func (c *Client) signer() Signer {
+	if c.sign == nil {
+		return c.merchant
+	}
+	return c.sign
+}
+
+// Format implements fmt.Formatter for c. It prints only merchant id,
+// so credentials are never printed with Client
+func (c Client) Format(s fmt.State, _ rune) {
+	fmt.Fprintf(s, "p24.Client{Merchant:%q}", c.merchant.ID)
+}
+
+type signerKey struct{}
+
+// callSigner returns Signer the call request was signed with or Client Signer
+func (c *Client) callSigner(ctx context.Context) Signer {
+	if s, ok := ctx.Value(signerKey{}).(Signer); ok {
+		return s
+	}
+	return c.signer()
+}
+
+// endpoint returns a name of endpoint with given url or empty string if url is unknown
+func (c *Client) endpoint(url string) Endpoint {
+	for _, endpoint := range Endpoints() {
+		if c.endpointURL(endpoint) == url {
+			return endpoint
+		}
+	}
+	return ""
+}
+
+// endpointURL returns url of given endpoint
+func (c *Client) endpointURL(endpoint Endpoint) string {
+	if u, ok := c.urls[endpoint]; ok {
+		return u
 	}
+	return DefaultBaseURL + endpointPaths[endpoint]
+}
+
+// do signs reqData and performs p24 api call of endpoint within spanName span.
+// The verified response is checked by check in strict verification
+// and its raw '<data>' content is stored to data if it is not nil
+func (c *Client) do(ctx context.Context, spanName string, endpoint Endpoint, reqData RequestData,
+	check responseCheck, data *[]byte, resp *Response) (err error) {
+	ctx, span := c.tracer().Start(ctx, spanName, F("endpoint", endpoint))
+	defer func() { span.End(err) }()
+
+	signCtx, signSpan := c.tracer().Start(ctx, SpanMarshalSign)
+	signer := c.signer()
+	if s, ok := signer.(snapshotSigner); ok {
+		if signer, err = s.snapshot(signCtx); err != nil {
+			signSpan.End(err)
+			return err
+		}
+		ctx = context.WithValue(ctx, signerKey{}, signer)
+	}
+	req, err := NewSignedRequest(signCtx, signer, reqData)
+	signSpan.End(err)
+	if err != nil {
+		return err
+	}
+
+	return c.doCall(ctx, Call{URL: c.endpointURL(endpoint), Method: http.MethodPost, Req: req, check: check, data: data}, resp)
 }
 
 // DoContext performs a p24 http api call with given url, method, request
-// and unmarshal response body to resp if no errors occurred
-// nolint:gocyclo // Is a complexity function
+// and unmarshal response body to resp if no errors occurred.
+// The call is passed through Client middlewares and
+// failed attempts are retried according to Client RetryPolicy
 func (c *Client) DoContext(ctx context.Context, url, method string, req Request, resp *Response) error {
-	// process http req
-	httpReqBody, err := xml.Marshal(req)
+	return c.doCall(ctx, Call{URL: url, Method: method, Req: req}, resp)
+}
+
+// doCall performs call like DoContext, Endpoint of the call is resolved by its URL
+func (c *Client) doCall(ctx context.Context, call Call, resp *Response) (err error) {
+	ctx, span := c.tracer().Start(ctx, SpanDoContext, F("url", call.URL), F("method", call.Method))
+	defer func() { span.End(err) }()
+
+	call.Endpoint = c.endpoint(call.URL)
+	span.SetAttributes(F("endpoint", call.Endpoint))
+	return Chain(c.mws...)(c.handle)(ctx, call, resp)
+}
+
+// handle is the innermost Handler of Client middlewares chain
+func (c *Client) handle(ctx context.Context, call Call, resp *Response) error {
+	httpReqBody, err := xml.Marshal(call.Req)
 	if err != nil {
 		return errors.Wrap(err, "can`t marshal req")
 	}
 	httpReqBody = []byte(xml.Header + string(httpReqBody)) // insert xml header above
-	httpReq, err := http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(httpReqBody))
+
+	attempts := c.retry.attempts()
+	for attempt := 1; ; attempt++ {
+		err = c.doContext(ctx, call, attempt, httpReqBody, resp)
+		// a deadline of the caller context is not retried, but per attempt timeouts of http client are
+		if err == nil || attempt >= attempts || ctx.Err() != nil || !c.retry.retryable(err) {
+			return err
+		}
+
+		c.observeRetry(call.Endpoint)
+		backoff := c.retry.Backoff(attempt)
+		c.logEvent(ctx, LevelInfo, "p24 request retry",
+			F("endpoint", call.Endpoint), F("attempt", attempt), F("attempts", attempts), F("backoff", backoff), F("err", err))
+		if sleepErr := sleep(ctx, backoff); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// doContext performs a single attempt of p24 http api call
+func (c *Client) doContext(ctx context.Context, call Call, attempt int, httpReqBody []byte, resp *Response) (err error) {
+	// the merchant turn is taken before the breaker is asked, so waiting in the queue
+	// is not reported as endpoint failure and doesn`t hold a half-open trial slot
+	if c.limiter != nil {
+		merchantID := call.Req.MerchantSign.ID
+		if merchantID == "" {
+			merchantID = c.merchant.ID
+		}
+		release, err := c.limiter.Acquire(ctx, merchantID)
+		if err != nil {
+			c.observeRejected(call.Endpoint, errKindLimiter)
+			return err
+		}
+		defer release()
+	}
+
+	if b, ok := c.breakers[call.Endpoint]; ok {
+		generation, allowErr := b.allow()
+		if allowErr != nil {
+			c.observeRejected(call.Endpoint, errKindCircuitOpen)
+			return errors.Wrapf(allowErr, "%s endpoint", call.Endpoint)
+		}
+		defer func() { b.done(generation, err) }()
+	}
+
+	ctx, span := c.tracer().Start(ctx, SpanAttempt, F("endpoint", call.Endpoint), F("attempt", attempt))
+	status, start := 0, time.Now()
+	defer func() {
+		c.observeAttempt(call.Endpoint, status, time.Since(start).Seconds(), err)
+		span.End(err)
+	}()
+
+	c.logEvent(ctx, LevelDebug, "p24 request started",
+		F("endpoint", call.Endpoint), F("method", call.Method), F("url", call.URL), F("attempt", attempt))
+	status, header, httpRespBody, err := c.roundTrip(ctx, call, httpReqBody)
+	if err != nil {
+		err = c.attemptError(err, call, attempt, time.Since(start), status, header, httpReqBody, httpRespBody)
+	}
+	if status == 0 {
+		c.logEvent(ctx, LevelDebug, "p24 request failed", F("endpoint", call.Endpoint), F("latency", time.Since(start)), F("err", err))
+		return err
+	}
+	c.logEvent(ctx, LevelDebug, "p24 response received",
+		F("endpoint", call.Endpoint), F("status", status), F("latency", time.Since(start)), F("bytes", len(httpRespBody)))
 	if err != nil {
-		return errors.Wrap(err, "can`t make http request")
+		return err
+	}
+
+	if err = c.checkResp(ctx, call, httpReqBody, httpRespBody, resp); err != nil {
+		err = c.attemptError(err, call, attempt, time.Since(start), status, header, httpReqBody, httpRespBody)
+		c.logEvent(ctx, LevelDebug, "p24 response rejected", F("endpoint", call.Endpoint), F("err", err))
+		return err
+	}
+	c.logEvent(ctx, LevelDebug, "p24 response verified", F("endpoint", call.Endpoint))
+	return nil
+}
+
+// attemptError returns *Error of failed attempt of call with redacted message and bodies
+func (c *Client) attemptError(err error, call Call, attempt int, elapsed time.Duration,
+	status int, header http.Header, httpReqBody, httpRespBody []byte) *Error {
+	e := newError(err, call.URL, call.Method, c.redact.body(httpReqBody), c.redact.body(httpRespBody))
+	e.Err = c.redact.redactError(err)
+	e.Endpoint, e.Attempt, e.Elapsed = call.Endpoint, attempt, elapsed
+	e.StatusCode, e.Header = status, errorHeader(header)
+	var sigErr *SignatureError
+	if errors.As(err, &sigErr) {
+		e.diagnostics = sigErr.Diagnostics.redactedString(c.redact)
+	}
+	return e
+}
+
+// roundTrip sends http request with httpReqBody and returns response status code, header and body.
+// It returns an error if the request failed or response status code is unexpected
+func (c *Client) roundTrip(ctx context.Context, call Call, httpReqBody []byte) (status int, header http.Header, httpRespBody []byte, err error) {
+	url, method := call.URL, call.Method
+	ctx, span := c.tracer().Start(ctx, SpanRoundTrip, F("method", method), F("url", url))
+	defer func() {
+		span.SetAttributes(F("status", status), F("bytes", len(httpRespBody)))
+		span.End(err)
+	}()
+
+	// process http req
+	httpReq, err := http.NewRequestWithContext(c.withClientTrace(ctx, span), method, url, bytes.NewReader(httpReqBody))
+	if err != nil {
+		return 0, nil, nil, errors.Wrap(err, "can`t make http request")
 	}
 	httpReq.Header.Add("Content-Type", "application/xml; charset=utf-8")
 
 	// process http resp
 	httpResp, err := c.http.Do(httpReq)
 	if err != nil {
-		return newError(errors.Wrap(err, "http request failed"), url, method, httpReqBody, nil)
+		return 0, nil, nil, &transportError{errors.Wrap(err, "http request failed")}
 	}
 	defer func() {
-		if err = httpResp.Body.Close(); err != nil {
-			c.log.Logf("[WARN] failed to close http response body: %+v\n", err)
+		if err := httpResp.Body.Close(); err != nil {
+			c.logEvent(ctx, LevelWarn, "failed to close http response body", F("endpoint", call.Endpoint), F("err", err))
 		}
 	}()
-	httpRespBody, err := io.ReadAll(httpResp.Body)
+	status, header = httpResp.StatusCode, httpResp.Header
+	httpRespBody, err = c.readBody(httpResp)
+	if err != nil {
+		return status, header, nil, errors.Wrap(err, "can`t read http response body")
+	}
+	if status >= 300 {
+		return status, header, httpRespBody, &statusCodeError{status}
+	}
+	return status, header, httpRespBody, nil
+}
+
+// readBody reads httpResp body with respect to Client max response bytes.
+// It returns *LimitError if the body is too large
+func (c *Client) readBody(httpResp *http.Response) ([]byte, error) {
+	if c.maxBytes <= 0 {
+		body, err := io.ReadAll(httpResp.Body)
+		if err != nil {
+			return nil, &transportError{err}
+		}
+		return body, nil
+	}
+
+	if httpResp.ContentLength > c.maxBytes {
+		return nil, &LimitError{LimitResponseBytes, c.maxBytes}
+	}
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, c.maxBytes+1))
 	if err != nil {
-		return newError(errors.Wrap(err, "can`t read http response body"), url, method, httpReqBody, nil)
+		return nil, &transportError{err}
 	}
-	if httpResp.StatusCode >= 300 {
-		return newError(errors.Errorf("unexpected http status code %d", httpResp.StatusCode), url, method, httpReqBody, httpRespBody)
+	if int64(len(body)) > c.maxBytes {
+		return nil, &LimitError{LimitResponseBytes, c.maxBytes}
 	}
+	return body, nil
+}
 
-	// parse xml resp
-	xmlResp := xmlResp(httpRespBody)
-	if err = xmlResp.CheckErr(); err != nil {
-		return newError(errors.Wrap(err, "xml response with error"), url, method, httpReqBody, httpRespBody)
+// checkResp parses, checks and verifies signature of p24 http response body,
+// appends it to audit sink and unmarshal it to resp if no errors occurred.
+// Raw verified data is stored to data of call if it is set
+func (c *Client) checkResp(ctx context.Context, call Call, httpReqBody, httpRespBody []byte, resp *Response) error {
+	_, span := c.tracer().Start(ctx, SpanParseDecode, F("bytes", len(httpRespBody)))
+	parsed, err := parseResp(httpRespBody, *resp, c.limits)
+	span.End(err)
+	if err != nil {
+		return &classError{errors.Wrap(err, "unexpected xml response content"), ErrInvalidContent}
+	}
+	if parsed.respErr != nil {
+		return errors.Wrap(parsed.respErr, "xml response with error")
+	}
+	verifyCtx, span := c.tracer().Start(ctx, SpanVerify)
+	expected, err := c.callSigner(ctx).SignData(verifyCtx, parsed.data)
+	if err != nil {
+		span.End(err)
+		return errors.Wrap(err, "can`t sign response data")
+	}
+	err = verifySign(expected, parsed.resp.MerchantSign)
+	span.End(err)
+	if err != nil {
+		if c.signDiagnostics {
+			return &SignatureError{diagnose(parsed.data, parsed.dataStart, expected, parsed.resp.MerchantSign)}
+		}
+		return &classError{errors.New("xml response with invalid signature"), ErrInvalidSignature}
+	}
+	if parsed.decodeErr != nil {
+		return &classError{errors.Wrap(parsed.decodeErr, "can`t unmarshal xml response"), ErrDecode}
 	}
-	if err = xmlResp.CheckContent(); err != nil {
-		return newError(errors.Wrap(err, "unexpected xml response content"), url, method, httpReqBody, httpRespBody)
+	if err := c.checkStrict(call, parsed.resp); err != nil {
+		return err
 	}
-	if err = xmlResp.VerifySign(c.merchant); err != nil {
-		return newError(errors.New("xml response with invalid signature"), url, method, httpReqBody, httpRespBody)
+	if err := c.appendAudit(ctx, call, httpReqBody, parsed.data, parsed.resp.MerchantSign); err != nil {
+		return err
 	}
-	if err = xml.Unmarshal(xmlResp, resp); err != nil {
-		return newError(errors.Wrap(err, "can`t unmarshal xml response"), url, method, httpReqBody, httpRespBody)
+	*resp = parsed.resp
+	if call.data != nil {
+		*call.data = parsed.data
 	}
 
 	return nil
@@ -30,20 +30,31 @@ func TestNewClient(t *testing.T) {
 		Pass: "pass",
 	}
 
-	cli := NewClient(ClientOpts{
+	cli, err := NewClient(ClientOpts{
 		HTTP:     doer,
 		Merchant: merchant,
 	})
+	require.NoError(t, err)
 	require.Equal(t, merchant, cli.merchant)
 	require.NotNil(t, cli.log)
+	require.Equal(t, "https://api.privatbank.ua/p24api/rest_fiz", cli.endpointURL(EndpointStatements))
 
 	req, _ := http.NewRequest(http.MethodPost, "http://localhost", http.NoBody)
 	_, _ = cli.http.Do(req)
 	require.Equal(t, http.MethodPost, doerOutput)
 
-	cli = NewClient(ClientOpts{Log: log})
-	cli.log.Logf("%s", "test")
-	require.Equal(t, "test", logOutput)
+	cli, err = NewClient(ClientOpts{Log: log})
+	require.NoError(t, err)
+	cli.log.Log(context.Background(), LevelInfo, "test")
+	require.Equal(t, "[INFO] test\n", logOutput)
+
+	_, err = NewClient(ClientOpts{BaseURL: "localhost"})
+	require.ErrorContains(t, err, "invalid base url")
+
+	require.Equal(t, merchant, MustNewClient(ClientOpts{HTTP: doer, Merchant: merchant}).merchant)
+	require.PanicsWithError(t, "invalid client opts: invalid base url: unsupported scheme \"\"", func() {
+		MustNewClient(ClientOpts{BaseURL: "localhost"})
+	})
 }
 
 func TestClient_DoContext(t *testing.T) {
@@ -171,7 +182,7 @@ func TestClient_DoContext(t *testing.T) {
 				return tr.Result(), nil
 			}
 
-			cli := Client{do, nil, c.merchant}
+			cli := Client{http: do, merchant: c.merchant}
 			actual := c.expected
 			if err := cli.DoContext(context.Background(), url, method, req, &actual); err != nil {
 				require.NotEmpty(t, c.errMsg)
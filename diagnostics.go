@@ -0,0 +1,155 @@
+package p24
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// diagnosticsEdge is a number of leading/trailing bytes of signed data shown by SignDiagnostics
+const diagnosticsEdge = 32
+
+// SignDiagnostics explains signature verification of p24 response.
+// It contains raw signed data, so it is intended for investigation only
+type SignDiagnostics struct {
+	// Data is the signed content of '<response><data>' tag
+	Data []byte
+
+	// Start and End are offsets of Data in the response body
+	Start, End int
+
+	// Expected is a signature of Data computed with Merchant password,
+	// Received is a signature of the response
+	Expected, Received string
+
+	// MerchantID is an id of Merchant, RespMerchantID is a merchant id of the response
+	MerchantID, RespMerchantID string
+}
+
+// Valid reports whether the response signature and merchant id are matched
+func (d SignDiagnostics) Valid() bool {
+	return d.Expected == d.Received && d.MerchantID == d.RespMerchantID
+}
+
+// Problems returns descriptions of found signature problems
+func (d SignDiagnostics) Problems() []string {
+	return d.problems(Redaction{KeepMerchantID: true})
+}
+
+// problems returns descriptions of found signature problems,
+// merchant ids are left out of them unless r keeps merchant ids
+func (d SignDiagnostics) problems(r Redaction) []string {
+	var problems []string
+	if d.MerchantID != d.RespMerchantID {
+		if r.KeepMerchantID {
+			problems = append(problems, fmt.Sprintf("merchant id mismatch: ours %q, response %q", d.MerchantID, d.RespMerchantID))
+		} else {
+			problems = append(problems, "merchant id mismatch")
+		}
+	}
+	if d.Expected == d.Received {
+		return problems
+	}
+	problems = append(problems, "signature mismatch")
+	if d.Received == "" {
+		problems = append(problems, "response has no signature")
+	}
+	if len(d.Data) == 0 {
+		problems = append(problems, "signed data is empty")
+	}
+	if trimmed := strings.TrimSpace(string(d.Data)); len(trimmed) != len(d.Data) {
+		problems = append(problems, "signed data has leading or trailing whitespace")
+	}
+	if !utf8.Valid(d.Data) {
+		problems = append(problems, "signed data is not valid utf-8")
+	}
+	if strings.Contains(string(d.Data), "\r") {
+		problems = append(problems, "signed data contains carriage returns")
+	}
+	return problems
+}
+
+// String returns multiline report of d
+func (d SignDiagnostics) String() string {
+	head, tail := d.Data, d.Data
+	if len(head) > diagnosticsEdge {
+		head, tail = head[:diagnosticsEdge], tail[len(tail)-diagnosticsEdge:]
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "signed bytes: [%d:%d] (%d bytes)\n", d.Start, d.End, len(d.Data))
+	fmt.Fprintf(b, "expected signature: %s\n", d.Expected)
+	fmt.Fprintf(b, "received signature: %s\n", d.Received)
+	fmt.Fprintf(b, "merchant id: ours %q, response %q\n", d.MerchantID, d.RespMerchantID)
+	fmt.Fprintf(b, "leading bytes: %q\n", head)
+	fmt.Fprintf(b, "leading hex: %s\n", hex.EncodeToString(head))
+	fmt.Fprintf(b, "trailing bytes: %q\n", tail)
+	fmt.Fprintf(b, "trailing hex: %s", hex.EncodeToString(tail))
+	for _, problem := range d.Problems() {
+		fmt.Fprintf(b, "\nproblem: %s", problem)
+	}
+	return b.String()
+}
+
+// redactedString returns report of d redacted by r. It is a full report if r keeps raw bodies,
+// otherwise signed data and signatures are left out and merchant ids are masked
+func (d SignDiagnostics) redactedString(r Redaction) string {
+	if r.KeepRawBodies {
+		return d.String()
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "signed bytes: [%d:%d] (%d bytes)\n", d.Start, d.End, len(d.Data))
+	fmt.Fprintf(b, "merchant id: ours %q, response %q", r.merchantID(d.MerchantID), r.merchantID(d.RespMerchantID))
+	for _, problem := range d.problems(r) {
+		fmt.Fprintf(b, "\nproblem: %s", r.Redact(problem))
+	}
+	return b.String()
+}
+
+// diagnose returns SignDiagnostics of data which starts at start offset of response body
+// with expected and received dataSign signatures
+func diagnose(data []byte, start int, expected, dataSign MerchantSign) SignDiagnostics {
+	return SignDiagnostics{
+		Data:           data,
+		Start:          start,
+		End:            start + len(data),
+		Expected:       expected.Sign,
+		Received:       dataSign.Sign,
+		MerchantID:     expected.ID,
+		RespMerchantID: dataSign.ID,
+	}
+}
+
+// ExplainVerify returns SignDiagnostics of raw p24 http response body, like saved one.
+// It returns an error if raw is not a p24 response with '<data>' tag
+func (m Merchant) ExplainVerify(raw []byte) (SignDiagnostics, error) {
+	parsed, err := parseResp(raw, Response{}, xmlLimits{})
+	if err != nil {
+		return SignDiagnostics{}, errors.Wrap(err, "unexpected xml response content")
+	}
+	if parsed.data == nil {
+		return SignDiagnostics{}, errors.Wrap(parsed.respErr, "xml response without '<data>' tag")
+	}
+	return diagnose(parsed.data, parsed.dataStart, m.Sign(parsed.data), parsed.resp.MerchantSign), nil
+}
+
+// SignatureError reports a p24 response with invalid signature and its SignDiagnostics.
+// It is returned by Client with enabled signature diagnostics.
+// Merchant ids are left out of its message, they are available in Diagnostics
+type SignatureError struct {
+	Diagnostics SignDiagnostics
+}
+
+func (e *SignatureError) Error() string {
+	msg := "xml response with invalid signature"
+	if problems := e.Diagnostics.problems(Redaction{}); len(problems) > 0 {
+		msg += ": " + strings.Join(problems, ", ")
+	}
+	return msg
+}
+
+func (e *SignatureError) Is(target error) bool { return target == ErrInvalidSignature }
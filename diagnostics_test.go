@@ -0,0 +1,190 @@
+package p24
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerchant_ExplainVerify(t *testing.T) {
+	const (
+		data = `<info><test>test</test></info><oper>cmt</oper>`
+		sign = "ad67cf1c11e0f87bedac2c9bb260e3abf54e9862"
+	)
+	m := Merchant{"id", "pass"}
+	body := func(data, id, sign string) []byte {
+		return []byte(fmt.Sprintf(`%s<response><data>%s</data><merchant><id>%s</id><signature>%s</signature></merchant></response>`,
+			xml.Header, data, id, sign))
+	}
+
+	cases := []struct {
+		raw      []byte
+		problems []string
+		errMsg   string
+		valid    bool
+	}{
+		{raw: body(data, "id", sign), valid: true},
+		{
+			raw:      body(data, "other", sign),
+			problems: []string{`merchant id mismatch: ours "id", response "other"`},
+		},
+		{
+			raw:      body(data, "id", "0000000000000000000000000000000000000000"),
+			problems: []string{"signature mismatch"},
+		},
+		{
+			raw:      body(data, "id", ""),
+			problems: []string{"signature mismatch", "response has no signature"},
+		},
+		{
+			raw:      body("\n"+data+"\r\n", "id", sign),
+			problems: []string{"signature mismatch", "signed data has leading or trailing whitespace", "signed data contains carriage returns"},
+		},
+		{raw: []byte(`<response>`), errMsg: "unexpected xml response content: XML syntax error on line 1: unexpected EOF"},
+		{raw: []byte(`<error>For input string: "x"</error>`), errMsg: `xml response without '<data>' tag: For input string: "x"`},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			d, err := m.ExplainVerify(c.raw)
+			if c.errMsg != "" {
+				require.EqualError(t, err, c.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.valid, d.Valid())
+			require.Equal(t, c.problems, d.Problems())
+			require.Equal(t, string(c.raw[d.Start:d.End]), string(d.Data))
+			require.Equal(t, m.ID, d.MerchantID)
+		})
+	}
+}
+
+func TestSignDiagnostics_String(t *testing.T) {
+	d := SignDiagnostics{
+		Data:           []byte("\t<oper>cmt</oper>" + strings.Repeat("x", 40) + "<info/>\n"),
+		Start:          10,
+		End:            75,
+		Expected:       "aa",
+		Received:       "bb",
+		MerchantID:     "id",
+		RespMerchantID: "id",
+	}
+	require.Equal(t, `signed bytes: [10:75] (65 bytes)
+expected signature: aa
+received signature: bb
+merchant id: ours "id", response "id"
+leading bytes: "\t<oper>cmt</oper>xxxxxxxxxxxxxxx"
+leading hex: 093c6f7065723e636d743c2f6f7065723e787878787878787878787878787878
+trailing bytes: "xxxxxxxxxxxxxxxxxxxxxxxx<info/>\n"
+trailing hex: 7878787878787878787878787878787878787878787878783c696e666f2f3e0a
+problem: signature mismatch
+problem: signed data has leading or trailing whitespace`, d.String())
+}
+
+func TestClient_DoContext_SignDiagnostics(t *testing.T) {
+	respBody := `<response><data><info><test>test</test></info><oper>cmt</oper></data>` +
+		`<merchant><id>id</id><signature>0000000000000000000000000000000000000000</signature></merchant></response>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(respBody))
+	}))
+	defer srv.Close()
+
+	for i, enabled := range []bool{false, true} {
+		enabled := enabled
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			cli, err := NewClient(ClientOpts{
+				HTTP:            http.DefaultClient,
+				BaseURL:         srv.URL + "/",
+				Merchant:        Merchant{"id", "pass"},
+				SignDiagnostics: enabled,
+			})
+			require.NoError(t, err)
+
+			type info struct {
+				Test string `xml:"test"`
+			}
+			err = cli.DoContext(context.Background(), cli.endpointURL(EndpointStatements), http.MethodPost, Request{},
+				&Response{Data: ResponseData{Info: info{}}})
+			require.ErrorIs(t, err, ErrInvalidSignature)
+
+			var sigErr *SignatureError
+			require.Equal(t, enabled, errors.As(err, &sigErr))
+			if !enabled {
+				require.EqualError(t, err, "xml response with invalid signature")
+				return
+			}
+			require.EqualError(t, err, "xml response with invalid signature: signature mismatch")
+			require.Equal(t, "ad67cf1c11e0f87bedac2c9bb260e3abf54e9862", sigErr.Diagnostics.Expected)
+			require.Equal(t, `<info><test>test</test></info><oper>cmt</oper>`, string(sigErr.Diagnostics.Data))
+			require.Equal(t, len("<response><data>"), sigErr.Diagnostics.Start)
+			verbose := fmt.Sprintf("%+v", err)
+			require.Contains(t, verbose, "  signed bytes: [16:62] (46 bytes)\n  merchant id: ours \"[REDACTED]\", response \"[REDACTED]\"\n  problem: signature mismatch")
+			require.NotContains(t, verbose, "ad67cf1c11e0f87bedac2c9bb260e3abf54e9862")
+			require.NotContains(t, verbose, "leading bytes")
+		})
+	}
+
+	t.Run("MerchantIDMismatch", func(t *testing.T) {
+		m := Merchant{"1234567", "pass"}
+		data := `<info><test>test</test></info><oper>cmt</oper>`
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(signedResponse(Merchant{"9999999", "other"}, "9999999", data)))
+		}))
+		defer srv.Close()
+
+		for _, redaction := range []Redaction{{}, {KeepMerchantID: true}} {
+			cli, err := NewClient(ClientOpts{
+				HTTP:            http.DefaultClient,
+				BaseURL:         srv.URL + "/",
+				Merchant:        m,
+				SignDiagnostics: true,
+				Redaction:       redaction,
+			})
+			require.NoError(t, err)
+
+			err = cli.DoContext(context.Background(), cli.endpointURL(EndpointStatements), http.MethodPost, Request{}, &Response{})
+			require.ErrorIs(t, err, ErrInvalidSignature)
+			require.EqualError(t, err, "xml response with invalid signature: merchant id mismatch, signature mismatch")
+			verbose := fmt.Sprintf("%+v", err)
+			for _, id := range []string{"1234567", "9999999"} {
+				require.NotContains(t, err.Error(), id)
+				require.Equal(t, redaction.KeepMerchantID, strings.Contains(verbose, id))
+			}
+		}
+	})
+
+	t.Run("KeepRawBodies", func(t *testing.T) {
+		cli, err := NewClient(ClientOpts{
+			HTTP:            http.DefaultClient,
+			BaseURL:         srv.URL + "/",
+			Merchant:        Merchant{"id", "pass"},
+			SignDiagnostics: true,
+			Redaction:       Redaction{KeepRawBodies: true},
+		})
+		require.NoError(t, err)
+
+		err = cli.DoContext(context.Background(), cli.endpointURL(EndpointStatements), http.MethodPost, Request{}, &Response{})
+		require.ErrorIs(t, err, ErrInvalidSignature)
+		verbose := fmt.Sprintf("%+v", err)
+		require.Contains(t, verbose, "  expected signature: ad67cf1c11e0f87bedac2c9bb260e3abf54e9862\n")
+		require.Contains(t, verbose, "  leading bytes: \"<info><test>test</test></info><o\"\n")
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		d := SignDiagnostics{Data: []byte("data"), End: 4, Expected: "ad67cf1c11e0f87bedac2c9bb260e3abf54e9862", MerchantID: "id", RespMerchantID: "id"}
+		verbose := fmt.Sprintf("%+v", &Error{Err: &SignatureError{d}})
+		require.Equal(t, "xml response with invalid signature: signature mismatch, response has no signature\n"+
+			"  signed bytes: [0:4] (4 bytes)\n  merchant id: ours \"[REDACTED]\", response \"[REDACTED]\"\n"+
+			"  problem: signature mismatch\n  problem: response has no signature", verbose)
+	})
+}
@@ -0,0 +1,93 @@
+package p24
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultBaseURL is a base url of p24 information api
+const DefaultBaseURL = "https://api.privatbank.ua/p24api/"
+
+// Endpoint is a name of p24 api endpoint
+type Endpoint string
+
+const (
+	// EndpointCardBalance is a p24 card balance endpoint.
+	// see: https://api.privatbank.ua/#p24/balance
+	EndpointCardBalance Endpoint = "balance"
+	// EndpointStatements is a p24 statements endpoint.
+	// see: https://api.privatbank.ua/#p24/orders
+	EndpointStatements Endpoint = "statements"
+)
+
+// endpointPaths stores paths of endpoints relative to base url
+var endpointPaths = map[Endpoint]string{
+	EndpointCardBalance: "balance",
+	EndpointStatements:  "rest_fiz",
+}
+
+// Endpoints returns all known p24 endpoints
+func Endpoints() []Endpoint {
+	return []Endpoint{EndpointCardBalance, EndpointStatements}
+}
+
+// resolveEndpointURLs returns absolute urls of all known endpoints.
+// An empty baseURL is replaced by DefaultBaseURL.
+// Every override can be an absolute url or a path relative to baseURL,
+// a leading "/" of the path is ignored, so it is relative to baseURL too.
+func resolveEndpointURLs(baseURL string, overrides map[Endpoint]string) (map[Endpoint]string, error) {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	base, err := parseAbsURL(baseURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid base url")
+	}
+	// make base url a "directory" so relative paths are joined instead of replacing last segment
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+
+	for endpoint := range overrides {
+		if _, ok := endpointPaths[endpoint]; !ok {
+			return nil, errors.Errorf("unknown endpoint %q", endpoint)
+		}
+	}
+
+	urls := make(map[Endpoint]string, len(endpointPaths))
+	for endpoint, path := range endpointPaths {
+		if override, ok := overrides[endpoint]; ok && override != "" {
+			path = override
+		}
+		ref, err := url.Parse(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %q endpoint url", endpoint)
+		}
+		if !ref.IsAbs() {
+			ref.Path = strings.TrimPrefix(ref.Path, "/")
+		}
+		resolved, err := parseAbsURL(base.ResolveReference(ref).String())
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %q endpoint url", endpoint)
+		}
+		urls[endpoint] = resolved.String()
+	}
+	return urls, nil
+}
+
+// parseAbsURL parses rawURL and returns an error if it is not an absolute http(s) url
+func parseAbsURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, errors.New("empty host")
+	}
+	return u, nil
+}
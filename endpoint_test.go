@@ -0,0 +1,61 @@
+package p24
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_resolveEndpointURLs(t *testing.T) {
+	cases := []struct {
+		overrides map[Endpoint]string
+		expected  map[Endpoint]string
+		baseURL   string
+		errMsg    string
+	}{
+		{
+			expected: map[Endpoint]string{
+				EndpointCardBalance: "https://api.privatbank.ua/p24api/balance",
+				EndpointStatements:  "https://api.privatbank.ua/p24api/rest_fiz",
+			},
+		},
+		{
+			baseURL: "http://localhost:8080/proxy/p24",
+			expected: map[Endpoint]string{
+				EndpointCardBalance: "http://localhost:8080/proxy/p24/balance",
+				EndpointStatements:  "http://localhost:8080/proxy/p24/rest_fiz",
+			},
+		},
+		{
+			baseURL: "http://localhost:8080/proxy/",
+			overrides: map[Endpoint]string{
+				EndpointCardBalance: "/custom/balance",
+				EndpointStatements:  "https://staging.example.com/rest_fiz?x=1",
+			},
+			expected: map[Endpoint]string{
+				EndpointCardBalance: "http://localhost:8080/proxy/custom/balance",
+				EndpointStatements:  "https://staging.example.com/rest_fiz?x=1",
+			},
+		},
+		{baseURL: "localhost:8080", errMsg: "invalid base url"},
+		{baseURL: "ftp://localhost", errMsg: "unsupported scheme"},
+		{baseURL: "http://", errMsg: "empty host"},
+		{overrides: map[Endpoint]string{"unknown": "/path"}, errMsg: `unknown endpoint "unknown"`},
+		{overrides: map[Endpoint]string{EndpointStatements: "ftp://host/path"}, errMsg: `invalid "statements" endpoint url`},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			actual, err := resolveEndpointURLs(c.baseURL, c.overrides)
+			if err != nil {
+				require.NotEmpty(t, c.errMsg)
+				require.ErrorContains(t, err, c.errMsg)
+				return
+			}
+			require.Empty(t, c.errMsg)
+			require.Equal(t, c.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package p24
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Sentinel errors of p24 api calls. Use errors.Is to check them,
+// it works through *Error and keeps the original error message
+var (
+	// ErrTransport is a failure of http round trip
+	ErrTransport = errors.New("transport failure")
+	// ErrHTTPStatus is an unexpected http status code of p24 response
+	ErrHTTPStatus = errors.New("unexpected http status code")
+	// ErrLimitExceeded is a p24 response that exceeds one of Client limits, see LimitError
+	ErrLimitExceeded = errors.New("response limit exceeded")
+	// ErrInvalidContent is a p24 response that is not a valid p24 xml response
+	ErrInvalidContent = errors.New("unexpected xml response content")
+	// ErrDecode is a p24 response which payload can`t be decoded
+	ErrDecode = errors.New("can`t unmarshal xml response")
+	// ErrInvalidSignature is a p24 response with invalid signature
+	// or p24 error reporting invalid signature of request
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrP24 is any error reported by p24 in response
+	ErrP24 = errors.New("p24 error")
+	// ErrIPNotAllowed is a p24 error reporting that client ip is not allowed for the merchant
+	ErrIPNotAllowed = errors.New("ip is not allowed")
+	// ErrMerchantNotFound is a p24 error reporting unknown merchant
+	ErrMerchantNotFound = errors.New("merchant not found")
+	// ErrRateLimited is a p24 error reporting too frequent requests of the merchant
+	ErrRateLimited = errors.New("rate limited")
+	// ErrTemporary is a p24 error reporting a temporary p24 failure
+	ErrTemporary = errors.New("temporary failure")
+
+	// ErrInvalidDateRange is an invalid date range of request or p24 error reporting it
+	ErrInvalidDateRange = errors.New("invalid date range")
+	// ErrBadCardNumber is an invalid card number of request or p24 error reporting it
+	ErrBadCardNumber = errors.New("bad card number")
+
+	// ErrResponseMismatch is a verified p24 response that doesn`t match its request, see MismatchError
+	ErrResponseMismatch = errors.New("response doesn`t match request")
+)
+
+// errCatalog maps lower case parts of known p24 error messages to sentinel errors
+var errCatalog = []struct {
+	err    error
+	substr string
+}{
+	{ErrInvalidSignature, "invalid signature"},
+	{ErrIPNotAllowed, "ip is not allowed"},
+	{ErrIPNotAllowed, "ip not allowed"},
+	{ErrMerchantNotFound, "merchant not found"},
+	{ErrMerchantNotFound, "merchant is not found"},
+	{ErrMerchantNotFound, "unknown merchant"},
+	{ErrRateLimited, "too many requests"},
+	{ErrRateLimited, "too often"},
+	{ErrRateLimited, "rate limit"},
+	{ErrInvalidDateRange, "invalid date"},
+	{ErrInvalidDateRange, "date range"},
+	{ErrInvalidDateRange, "wrong date"},
+	{ErrBadCardNumber, "invalid card"},
+	{ErrBadCardNumber, "wrong card"},
+	{ErrBadCardNumber, "card not found"},
+	{ErrBadCardNumber, "card number"},
+	{ErrTemporary, "try again"},
+	{ErrTemporary, "temporarily"},
+	{ErrTemporary, "timeout"},
+	{ErrTemporary, "timed out"},
+	{ErrTemporary, "service unavailable"},
+	{ErrTemporary, "internal error"},
+	{ErrTemporary, "system error"},
+}
+
+// ClassifyMessage returns sentinel error matching a known p24 error message or nil if msg is unknown
+func ClassifyMessage(msg string) error {
+	msg = strings.ToLower(msg)
+	for _, c := range errCatalog {
+		if strings.Contains(msg, c.substr) {
+			return c.err
+		}
+	}
+	return nil
+}
+
+// isP24Error reports whether target is ErrP24 or sentinel of p24 error msg
+func isP24Error(msg string, target error) bool {
+	return target == ErrP24 || (target != nil && target == ClassifyMessage(msg))
+}
+
+// classError is an error with original message that matches a sentinel error
+type classError struct {
+	err   error
+	class error
+}
+
+func (e *classError) Error() string { return e.err.Error() }
+
+func (e *classError) Unwrap() error { return e.err }
+
+func (e *classError) Is(target error) bool { return target == e.class }
+
+// transportError reports a failure of http round trip
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string { return e.err.Error() }
+
+func (e *transportError) Unwrap() error { return e.err }
+
+func (e *transportError) Is(target error) bool { return target == ErrTransport }
+
+// statusCodeError reports an unexpected http status code
+type statusCodeError struct {
+	code int
+}
+
+func (e *statusCodeError) Error() string {
+	return fmt.Sprintf("unexpected http status code %d", e.code)
+}
+
+func (e *statusCodeError) Is(target error) bool { return target == ErrHTTPStatus }
+
+// ErrorHeaders are names of http response headers kept in Error.Header
+var ErrorHeaders = []string{"Content-Type", "Content-Length", "Date", "Retry-After", "Server", "X-Request-Id"}
+
+// Error reports an error of p24 api call attempt and the request/response that caused it
+type Error struct {
+	Err error
+	// Class is the most specific sentinel error that Err matches or nil if it matches none of them
+	Class       error
+	URL, Method string
+	Endpoint    Endpoint
+	Req, Resp   []byte
+	// Header contains ErrorHeaders of http response if it was received
+	Header http.Header
+	// StatusCode is http response status code or zero if it was not received
+	StatusCode int
+	// Attempt is a number of the failed attempt starting from 1
+	Attempt int
+	// Elapsed is a duration of the failed attempt
+	Elapsed time.Duration
+
+	// diagnostics is a redacted report of SignatureError of Err printed by %+v
+	diagnostics string
+}
+
+func (e *Error) Error() (err string) {
+	// nolint:gocritic // e.Err can be nil
+	return fmt.Sprint(e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func (e *Error) Cause() error { return e.Err }
+
+// Format implements fmt.Formatter for e.
+// %s and %v print the error message, %q prints it quoted,
+// %+v prints the message followed by call details and request/response bodies.
+// Signed data and signatures of SignatureError are printed only if Client Redaction keeps raw bodies
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		_, _ = io.WriteString(s, e.verbose())
+	case verb == 'v' || verb == 's':
+		_, _ = io.WriteString(s, e.Error())
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		fmt.Fprintf(s, "%%!%c(*p24.Error=%s)", verb, e.Error())
+	}
+}
+
+// verbose returns multiline diagnostic of e
+func (e *Error) verbose() string {
+	var b strings.Builder
+	b.WriteString(e.Error())
+	if e.Class != nil {
+		fmt.Fprintf(&b, "\n  class: %s", e.Class)
+	}
+	if e.Endpoint != "" {
+		fmt.Fprintf(&b, "\n  endpoint: %s", e.Endpoint)
+	}
+	if e.Method != "" || e.URL != "" {
+		fmt.Fprintf(&b, "\n  request: %s %s", e.Method, e.URL)
+	}
+	if e.Attempt > 0 {
+		fmt.Fprintf(&b, "\n  attempt: %d", e.Attempt)
+	}
+	if e.Elapsed > 0 {
+		fmt.Fprintf(&b, "\n  elapsed: %s", e.Elapsed)
+	}
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, "\n  status: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	keys := make([]string, 0, len(e.Header))
+	for key := range e.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "\n  header: %s: %s", key, strings.Join(e.Header[key], ", "))
+	}
+	if e.Req != nil {
+		fmt.Fprintf(&b, "\n  request body: %s", e.Req)
+	}
+	if e.Resp != nil {
+		fmt.Fprintf(&b, "\n  response body: %s", e.Resp)
+	}
+	diagnostics := e.diagnostics
+	var sigErr *SignatureError
+	if diagnostics == "" && errors.As(e.Err, &sigErr) {
+		diagnostics = sigErr.Diagnostics.redactedString(Redaction{})
+	}
+	if diagnostics != "" {
+		fmt.Fprintf(&b, "\n  %s", strings.ReplaceAll(diagnostics, "\n", "\n  "))
+	}
+	return b.String()
+}
+
+func newError(err error, url, method string, req, resp []byte) *Error {
+	return &Error{Err: err, Class: errorClass(err), URL: url, Method: method, Req: req, Resp: resp}
+}
+
+// errorClasses are sentinel errors ordered from the most specific ones
+var errorClasses = []error{
+	context.Canceled, context.DeadlineExceeded, ErrLimitExceeded, ErrTransport, ErrHTTPStatus, ErrInvalidContent,
+	ErrInvalidSignature, ErrResponseMismatch, ErrIPNotAllowed, ErrMerchantNotFound, ErrRateLimited, ErrTemporary,
+	ErrInvalidDateRange, ErrBadCardNumber, ErrP24, ErrDecode,
+}
+
+// errorClass returns the most specific sentinel error that err matches or nil
+func errorClass(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, class := range errorClasses {
+		if errors.Is(err, class) {
+			return class
+		}
+	}
+	return nil
+}
+
+// errorHeader returns ErrorHeaders of header or nil if there are none of them
+func errorHeader(header http.Header) http.Header {
+	var selected http.Header
+	for _, key := range ErrorHeaders {
+		if values := header.Values(key); len(values) > 0 {
+			if selected == nil {
+				selected = http.Header{}
+			}
+			selected[http.CanonicalHeaderKey(key)] = values
+		}
+	}
+	return selected
+}
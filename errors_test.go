@@ -0,0 +1,181 @@
+package p24
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyMessage(t *testing.T) {
+	cases := []struct {
+		msg      string
+		expected error
+	}{
+		{"invalid signature", ErrInvalidSignature},
+		{"this ip is not allowed: 127.0.0.1", ErrIPNotAllowed},
+		{"Merchant not found", ErrMerchantNotFound},
+		{"Too many requests, try later", ErrRateLimited},
+		{"Invalid date range", ErrInvalidDateRange},
+		{"wrong card number", ErrBadCardNumber},
+		{"Service temporarily unavailable", ErrTemporary},
+		{"some unknown error", nil},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require.Equal(t, c.expected, ClassifyMessage(c.msg))
+		})
+	}
+}
+
+func TestClient_DoContext_Errors(t *testing.T) {
+	okBody := xml.Header + `<response><data><info><test>test</test></info><oper>cmt</oper></data><merchant><id>id</id><signature>ad67cf1c11e0f87bedac2c9bb260e3abf54e9862</signature></merchant></response>`
+	cases := []struct {
+		doErr    error
+		sentinel error
+		body     string
+		errMsg   string
+		code     int
+	}{
+		{doErr: errors.New("connection reset"), sentinel: ErrTransport, errMsg: "http request failed: connection reset"},
+		{code: 502, sentinel: ErrHTTPStatus, errMsg: "unexpected http status code 502"},
+		{code: 200, body: `<response>`, sentinel: ErrInvalidContent, errMsg: "unexpected xml response content: XML syntax error"},
+		{code: 200, body: `<error>For input string: "x"</error>`, sentinel: ErrP24, errMsg: `xml response with error: For input string: "x"`},
+		{code: 200, body: `<response><data><error message="invalid signature"/></data></response>`, sentinel: ErrInvalidSignature, errMsg: "xml response with error: invalid signature"},
+		{code: 200, body: `<response><data><oper>cmt</oper><info>this ip is not allowed</info></data></response>`, sentinel: ErrIPNotAllowed, errMsg: "xml response with error: this ip is not allowed"},
+		{code: 200, body: `<response><data><error message="merchant not found"/></data></response>`, sentinel: ErrMerchantNotFound, errMsg: "merchant not found"},
+		{code: 200, body: `<response><data><error message="Too many requests"/></data></response>`, sentinel: ErrRateLimited, errMsg: "Too many requests"},
+		{code: 200, body: `<response><data><error message="invalid date range"/></data></response>`, sentinel: ErrInvalidDateRange, errMsg: "invalid date range"},
+		{code: 200, body: `<response><data><error message="invalid card"/></data></response>`, sentinel: ErrBadCardNumber, errMsg: "invalid card"},
+		{code: 200, body: `<response><data><info><test>test</test></info></data><merchant><id>id</id><signature>bad</signature></merchant></response>`, sentinel: ErrInvalidSignature, errMsg: "xml response with invalid signature"},
+		{code: 200, body: okBody, errMsg: ""},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			cli := Client{http: DoFunc(func(req *http.Request) (*http.Response, error) {
+				if c.doErr != nil {
+					return nil, c.doErr
+				}
+				tr := httptest.NewRecorder()
+				_, _ = tr.Write([]byte(c.body))
+				tr.Code = c.code
+				return tr.Result(), nil
+			}), merchant: Merchant{"id", "pass"}}
+
+			type info struct {
+				Test string `xml:"test"`
+			}
+			err := cli.DoContext(context.Background(), "http://localhost", http.MethodPost, Request{}, &Response{Data: ResponseData{Info: info{}}})
+			if c.errMsg == "" {
+				require.NoError(t, err)
+				return
+			}
+			var p24Err *Error
+			require.True(t, errors.As(err, &p24Err))
+			require.ErrorIs(t, err, c.sentinel)
+			require.ErrorContains(t, err, c.errMsg)
+		})
+	}
+}
+
+func Test_ValidationErrors(t *testing.T) {
+	cli := Client{}
+	_, err := cli.GetCardBalance(context.Background(), BalanceOpts{CardNumber: "123"})
+	require.ErrorIs(t, err, ErrBadCardNumber)
+	require.EqualError(t, err, "invalid card number: should be sixteen length")
+
+	_, err = cli.GetStatements(context.Background(), StatementsOpts{
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, kievLocation),
+		EndDate:    time.Date(2022, 1, 1, 0, 0, 0, 0, kievLocation),
+		CardNumber: "1234567890123456",
+	})
+	require.ErrorIs(t, err, ErrInvalidDateRange)
+	require.EqualError(t, err, "invalid request options: date range should be no longer than 90 days")
+
+	require.ErrorIs(t, &LimitError{LimitXMLDepth, 1}, ErrLimitExceeded)
+}
+
+func TestError_Format(t *testing.T) {
+	e := &Error{
+		Err:        &statusCodeError{502},
+		Class:      ErrHTTPStatus,
+		URL:        "http://localhost/balance",
+		Method:     http.MethodPost,
+		Endpoint:   EndpointCardBalance,
+		Req:        []byte("<request/>"),
+		Resp:       []byte("bad gateway"),
+		Header:     http.Header{"Retry-After": {"1"}, "Content-Type": {"text/plain"}},
+		StatusCode: 502,
+		Attempt:    2,
+		Elapsed:    1500 * time.Millisecond,
+	}
+	cases := []struct {
+		format   string
+		expected string
+	}{
+		{"%s", "unexpected http status code 502"},
+		{"%v", "unexpected http status code 502"},
+		{"%q", `"unexpected http status code 502"`},
+		{"%+v", "unexpected http status code 502\n" +
+			"  class: unexpected http status code\n" +
+			"  endpoint: balance\n" +
+			"  request: POST http://localhost/balance\n" +
+			"  attempt: 2\n" +
+			"  elapsed: 1.5s\n" +
+			"  status: 502 Bad Gateway\n" +
+			"  header: Content-Type: text/plain\n" +
+			"  header: Retry-After: 1\n" +
+			"  request body: <request/>\n" +
+			"  response body: bad gateway"},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require.Equal(t, c.expected, fmt.Sprintf(c.format, e))
+		})
+	}
+}
+
+func TestClient_DoContext_ErrorDetails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		w.Header().Set("Set-Cookie", "secret")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("unavailable"))
+	}))
+	defer srv.Close()
+
+	cli, err := NewClient(ClientOpts{
+		HTTP:     http.DefaultClient,
+		BaseURL:  srv.URL + "/",
+		Merchant: Merchant{"id", "pass"},
+		Retry:    RetryPolicy{MaxAttempts: 2},
+	})
+	require.NoError(t, err)
+
+	_, err = cli.GetCardBalance(context.Background(), BalanceOpts{CardNumber: "1234567890123456"})
+	var p24Err *Error
+	require.True(t, errors.As(err, &p24Err))
+	require.Equal(t, ErrHTTPStatus, p24Err.Class)
+	require.Equal(t, EndpointCardBalance, p24Err.Endpoint)
+	require.Equal(t, srv.URL+"/balance", p24Err.URL)
+	require.Equal(t, http.StatusServiceUnavailable, p24Err.StatusCode)
+	require.Equal(t, 2, p24Err.Attempt)
+	require.Greater(t, int64(p24Err.Elapsed), int64(0))
+	require.Equal(t, "5", p24Err.Header.Get("Retry-After"))
+	require.Empty(t, p24Err.Header.Get("Set-Cookie"))
+	require.Contains(t, string(p24Err.Req), "<request")
+	require.Equal(t, "unavailable", string(p24Err.Resp))
+}
@@ -0,0 +1,100 @@
+package p24
+
+import (
+	"regexp"
+	"time"
+)
+
+// Filter reports whether s matches it. Nil Filter matches all statements
+type Filter func(s Statement) bool
+
+// Match reports whether s matches f
+func (f Filter) Match(s Statement) bool {
+	return f == nil || f(s)
+}
+
+// Apply returns statements matching f in their order
+func (f Filter) Apply(statements []Statement) []Statement {
+	var matched []Statement
+	for _, s := range statements {
+		if f.Match(s) {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
+
+// And returns Filter matching statements that match all filters
+func And(filters ...Filter) Filter {
+	return func(s Statement) bool {
+		for _, f := range filters {
+			if !f.Match(s) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns Filter matching statements that match any of filters
+func Or(filters ...Filter) Filter {
+	return func(s Statement) bool {
+		for _, f := range filters {
+			if f.Match(s) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not returns Filter matching statements that don`t match f
+func Not(f Filter) Filter {
+	return func(s Statement) bool { return !f.Match(s) }
+}
+
+// DateRange returns Filter matching statements with date in [from, to) range.
+// Zero from or to means the range is unbounded from that side
+func DateRange(from, to time.Time) Filter {
+	return func(s Statement) bool {
+		return (from.IsZero() || !s.Date.Before(from)) && (to.IsZero() || s.Date.Before(to))
+	}
+}
+
+// AmountRange returns Filter matching statements with amount in [min, max] range.
+// Empty currency matches amount of any currency, currency is compared ignoring case
+func AmountRange(currency string, min, max Amount) Filter {
+	return func(s Statement) bool {
+		return (currency == "" || sameCurrency(s.Amount.Currency, currency)) && min <= s.Amount.Amount && s.Amount.Amount <= max
+	}
+}
+
+// Currency returns Filter matching statements with amount of currency, currency is compared ignoring case
+func Currency(currency string) Filter {
+	return func(s Statement) bool { return sameCurrency(s.Amount.Currency, currency) }
+}
+
+// Credit returns Filter matching statements with positive card amount
+func Credit() Filter {
+	return func(s Statement) bool { return s.CardAmount.Amount > 0 }
+}
+
+// Debit returns Filter matching statements with negative card amount
+func Debit() Filter {
+	return func(s Statement) bool { return s.CardAmount.Amount < 0 }
+}
+
+// Terminal returns Filter matching statements of terminal
+func Terminal(terminal string) Filter {
+	return func(s Statement) bool { return s.Terminal == terminal }
+}
+
+// Description returns Filter matching statements with description matched by re
+func Description(re *regexp.Regexp) Filter {
+	return func(s Statement) bool { return re.MatchString(s.Description) }
+}
+
+// CardNumber returns Filter matching statements of card number
+func CardNumber(number string) Filter {
+	return func(s Statement) bool { return s.Card == number }
+}
@@ -0,0 +1,75 @@
+package p24
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// filterStatements are statements for filter and query tests
+var filterStatements = []Statement{
+	{
+		Card: "1111222233334444", Date: time.Date(2021, 1, 1, 10, 0, 0, 0, kievLocation), Terminal: "PrivatBank, 1",
+		Description: "ATM withdrawal", Amount: Funds{"UAH", -60000}, CardAmount: Funds{"UAH", -60000}, Rest: Funds{"UAH", 40000},
+	},
+	{
+		Card: "1111222233334444", Date: time.Date(2021, 1, 1, 12, 0, 0, 0, kievLocation), Terminal: "Cafe", Appcode: "123",
+		Description: "Coffee", Amount: Funds{"UAH", -5000}, CardAmount: Funds{"UAH", -5000}, Rest: Funds{"UAH", 35000},
+	},
+	{
+		Card: "5555666677778888", Date: time.Date(2021, 1, 2, 9, 0, 0, 0, kievLocation),
+		Description: "Salary", Amount: Funds{"UAH", 100000}, CardAmount: Funds{"UAH", 100000}, Rest: Funds{"UAH", 100000},
+	},
+	{
+		Card: "1111222233334444", Date: time.Date(2021, 1, 3, 0, 0, 0, 0, kievLocation), Terminal: "Bank, 2",
+		Description: "ATM abroad", Amount: Funds{"USD", -2000}, CardAmount: Funds{"UAH", -55000}, Rest: Funds{"UAH", -20000},
+	},
+}
+
+// pick returns filterStatements of indexes
+func pick(indexes ...int) []Statement {
+	var statements []Statement
+	for _, i := range indexes {
+		statements = append(statements, filterStatements[i])
+	}
+	return statements
+}
+
+func TestFilter(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2021, 1, d, 0, 0, 0, 0, kievLocation) }
+	cases := []struct {
+		filter   Filter
+		expected []Statement
+	}{
+		{nil, pick(0, 1, 2, 3)},
+		{And(), pick(0, 1, 2, 3)},
+		{Or(), nil},
+		{Not(nil), nil},
+		{DateRange(day(1), day(2)), pick(0, 1)},
+		{DateRange(time.Time{}, day(2)), pick(0, 1)},
+		{DateRange(day(2), time.Time{}), pick(2, 3)},
+		{AmountRange("UAH", -60000, -5000), pick(0, 1)},
+		{AmountRange("", -60000, -2000), pick(0, 1, 3)},
+		{Currency("USD"), pick(3)},
+		{Currency("usd"), pick(3)},
+		{AmountRange("uah", -60000, -5000), pick(0, 1)},
+		{Credit(), pick(2)},
+		{Debit(), pick(0, 1, 3)},
+		{Terminal("Cafe"), pick(1)},
+		{Description(regexp.MustCompile("^ATM")), pick(0, 3)},
+		{CardNumber("5555666677778888"), pick(2)},
+		{And(Debit(), Currency("UAH"), nil), pick(0, 1)},
+		{Or(Credit(), Terminal("Cafe")), pick(1, 2)},
+		{Not(Or(Credit(), Terminal("Cafe"))), pick(0, 3)},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require.Equal(t, c.expected, c.filter.Apply(filterStatements))
+		})
+	}
+}
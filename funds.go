@@ -85,6 +85,11 @@ type Funds struct {
 	Amount   Amount
 }
 
+// sameCurrency reports whether currency codes a and b are equal ignoring case
+func sameCurrency(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
 // MarshalText implements the encoding.TextMarshaler interface for funds
 func (f Funds) MarshalText() ([]byte, error) {
 	return []byte(fmt.Sprintf("%s %s", f.Amount.String(), f.Currency)), nil
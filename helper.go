@@ -1,7 +1,9 @@
 package p24
 
 import (
-	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
 	"regexp"
 
 	"github.com/pkg/errors"
@@ -9,25 +11,47 @@ import (
 
 var onlyNumbers = regexp.MustCompile(`^\d+$`)
 
-// CheckCardNumber returns an error if card number is not valid
+// CheckCardNumber returns an error matching ErrBadCardNumber if card number is not valid
 func CheckCardNumber(card string) error {
 	switch {
 	case len(card) != 16:
-		return errors.New("should be sixteen length")
+		return &classError{errors.New("should be sixteen length"), ErrBadCardNumber}
 	case !onlyNumbers.MatchString(card):
-		return errors.New("should contains digits only")
+		return &classError{errors.New("should contains digits only"), ErrBadCardNumber}
 	default:
 		return nil
 	}
 }
 
-func dataTagContent(data []byte) ([]byte, error) {
-	start, end := bytes.Index(data, []byte("<data>")), bytes.LastIndex(data, []byte("</data>"))
-	if start == -1 || end == -1 {
-		return nil, errors.New("not found")
+// signedData returns content of top-level '<data>' element of p24 xml request or response body
+// and its offset in body. Unlike plain search of '<data>' it is aware of attributes, namespace prefixes,
+// comments, CDATA sections and nested '<data>' elements. It returns an error if body is not a valid xml,
+// its root is not '<request>' or '<response>' or the root has no or several '<data>' elements.
+// It extracts data the same way as response parser does
+func signedData(body []byte) ([]byte, int, error) {
+	p := newRespParser(body, xmlLimits{})
+	root, err := p.nextStart()
+	if err != nil {
+		if err == io.EOF {
+			err = errors.New("root element not found")
+		}
+		return nil, 0, err
 	}
-	start += len("<data>")
-	cnt := make([]byte, end-start)
-	copy(cnt, data[start:end])
-	return cnt, nil
+	if root.Name.Local != "request" && root.Name.Local != "response" {
+		return nil, 0, errors.Errorf("unexpected root element <%s>", root.Name.Local)
+	}
+
+	skip := func(xml.StartElement) error { return p.d.Skip() }
+	return p.walkRoot(skip, skip)
+}
+
+// formatVerb returns format of verb with flags of s like %+v
+func formatVerb(s fmt.State, verb rune) string {
+	format := "%"
+	for _, flag := range "+-# 0" {
+		if s.Flag(int(flag)) {
+			format += string(flag)
+		}
+	}
+	return format + string(verb)
 }
@@ -1,7 +1,9 @@
 package p24
 
 import (
+	"encoding/xml"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -31,28 +33,72 @@ func Test_CheckCardNumber(t *testing.T) {
 	}
 }
 
-func Test_dataTagContent(t *testing.T) {
-	cases := []struct {
-		errMsg   string
-		payload  []byte
-		expected []byte
-	}{
-		{"", []byte("<data>payload</data>"), []byte("payload")},
-		{"", []byte("<data><data>payload</data></data>"), []byte("<data>payload</data>")},
-		{"not found", []byte("payload</data></data>"), nil},
-		{"not found", []byte("<data><data>payload"), nil},
+// signedDataCorpus is a conformance corpus of '<data>' extraction edge cases
+var signedDataCorpus = []struct {
+	name     string
+	body     string
+	expected string
+	errMsg   string
+}{
+	{name: "Plain", body: `<response><data>payload</data></response>`, expected: "payload"},
+	{name: "Request", body: `<request version="1.0"><merchant><id>1</id></merchant><data><oper>cmt</oper></data></request>`, expected: "<oper>cmt</oper>"},
+	{name: "XMLHeader", body: xml.Header + `<response><data>payload</data></response>`, expected: "payload"},
+	{name: "Attributes", body: `<response><data id="1" test='x'>payload</data></response>`, expected: "payload"},
+	{name: "NamespacePrefix", body: `<p:response xmlns:p="urn:p24"><p:data>payload</p:data></p:response>`, expected: "payload"},
+	{name: "DefaultNamespace", body: `<response xmlns="urn:p24"><data>payload</data></response>`, expected: "payload"},
+	{name: "Whitespace", body: "<response>\n\t<data>\n payload \n</data >\n</response>", expected: "\n payload \n"},
+	{name: "Empty", body: `<response><data></data></response>`, expected: ""},
+	{name: "SelfClosing", body: `<response><data/></response>`, expected: ""},
+	{name: "NestedData", body: `<response><data><info><data>x</data></info></data></response>`, expected: "<info><data>x</data></info>"},
+	{name: "CDATAWithEndTag", body: `<response><data><![CDATA[</data>]]>x</data></response>`, expected: "<![CDATA[</data>]]>x"},
+	{name: "CommentWithEndTag", body: `<response><data><!-- </data> -->x</data></response>`, expected: "<!-- </data> -->x"},
+	{name: "CommentOutside", body: `<!-- <data>fake</data> --><response><data>x</data></response>`, expected: "x"},
+	{name: "Entities", body: `<response><data>&lt;data&gt;&amp;</data></response>`, expected: "&lt;data&gt;&amp;"},
+	{name: "DataAfterMerchant", body: `<response><merchant><id>1</id></merchant><data>x</data></response>`, expected: "x"},
+	{name: "DataInOtherElement", body: `<response><other><data>fake</data></other><data>x</data></response>`, expected: "x"},
+	{name: "TrailingContent", body: `<response><data>x</data></response><data>fake</data>`, expected: "x"},
+	{name: "Duplicated", body: `<response><data>x</data><data>y</data></response>`, errMsg: "invalid '<data>' tag: duplicated"},
+	{name: "NotFound", body: `<response><other>x</other></response>`, errMsg: "invalid '<data>' tag: not found"},
+	{name: "OnlyNestedData", body: `<response><other><data>x</data></other></response>`, errMsg: "invalid '<data>' tag: not found"},
+	{name: "UnexpectedRoot", body: `<data>x</data>`, errMsg: "unexpected root element <data>"},
+	{name: "Unclosed", body: `<response><data>x`, errMsg: "XML syntax error on line 1: unexpected EOF"},
+	{name: "Mismatched", body: `<response><data>x</response></data>`, errMsg: "XML syntax error on line 1: element <data> closed by </response>"},
+	{name: "EmptyDocument", body: ``, errMsg: "root element not found"},
+}
+
+func Test_signedData(t *testing.T) {
+	for _, c := range signedDataCorpus {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			actual, start, err := signedData([]byte(c.body))
+			if c.errMsg != "" {
+				require.EqualError(t, err, c.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expected, string(actual))
+			require.Equal(t, c.expected, c.body[start:start+len(actual)])
+		})
 	}
+}
 
-	for i, c := range cases {
+// Test_signedData_parseResp checks that response parser extracts the same signed data
+// and rejects the same bodies as signedData
+func Test_signedData_parseResp(t *testing.T) {
+	for _, c := range signedDataCorpus {
 		c := c
-		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			actual, err := dataTagContent(c.payload)
-			if err != nil {
-				require.EqualError(t, err, c.errMsg, "errors not equal")
-			} else {
-				require.Empty(t, c.errMsg, "should be an error")
-				require.Equal(t, string(c.expected), string(actual), "payloads not equal")
+		if !strings.Contains(c.body, "<response") && !strings.Contains(c.body, ":response") {
+			continue
+		}
+		t.Run(c.name, func(t *testing.T) {
+			parsed, err := parseResp([]byte(c.body), Response{}, xmlLimits{})
+			if c.errMsg != "" {
+				require.EqualError(t, err, c.errMsg)
+				return
 			}
+			require.NoError(t, err)
+			require.Equal(t, c.expected, string(parsed.data))
+			require.Equal(t, c.expected, c.body[parsed.dataStart:parsed.dataStart+len(parsed.data)])
 		})
 	}
 }
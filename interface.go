@@ -1,6 +1,9 @@
 package p24
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 // Doer defines minimal http client interface
 type Doer interface {
@@ -21,5 +24,18 @@ type Logger interface {
 // LogFunc type is an adapter to allow the use of ordinary functions as Logger
 type LogFunc func(format string, args ...interface{})
 
-// Logf calls funds(id)
+// Logf calls f(format, args...)
 func (f LogFunc) Logf(format string, args ...interface{}) { f(format, args...) }
+
+// StructuredLogger defines leveled logger interface with key/value fields
+type StructuredLogger interface {
+	Log(ctx context.Context, level Level, msg string, fields ...Field)
+}
+
+// StructuredLogFunc type is an adapter to allow the use of ordinary functions as StructuredLogger
+type StructuredLogFunc func(ctx context.Context, level Level, msg string, fields ...Field)
+
+// Log calls f(ctx, level, msg, fields...)
+func (f StructuredLogFunc) Log(ctx context.Context, level Level, msg string, fields ...Field) {
+	f(ctx, level, msg, fields...)
+}
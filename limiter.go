@@ -0,0 +1,143 @@
+package p24
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// LimiterOpts is a full set of all parameters to initialize Limiter
+type LimiterOpts struct {
+	// MinInterval is a min interval between two requests of the same merchant.
+	// Requests are not throttled if zero
+	MinInterval time.Duration
+
+	// MaxConcurrent is a max number of in-flight requests of the same merchant.
+	// 1 is used if less than 1, so requests of a merchant are serialized by default
+	MaxConcurrent int
+}
+
+// LimiterStats represents a state of merchant requests queue
+type LimiterStats struct {
+	// Active is a number of in-flight requests
+	Active int
+	// Queued is a number of requests waiting for their turn
+	Queued int
+}
+
+// Limiter throttles and serializes p24 requests per Merchant.ID.
+// Callers are queued in FIFO order. Limiter is safe for concurrent use
+// and can be shared across several Client instances
+type Limiter struct {
+	merchants map[string]*merchantLimiter
+	opts      LimiterOpts
+	mu        sync.Mutex
+}
+
+type merchantLimiter struct {
+	rate   *rate.Limiter
+	queue  []chan struct{}
+	active int
+}
+
+// NewLimiter returns Limiter instance with given opts
+func NewLimiter(opts LimiterOpts) *Limiter {
+	if opts.MaxConcurrent < 1 {
+		opts.MaxConcurrent = 1
+	}
+	return &Limiter{
+		merchants: map[string]*merchantLimiter{},
+		opts:      opts,
+	}
+}
+
+// Acquire blocks until merchantID is allowed to perform a request or ctx is done.
+// Returned release func must be called once the request is completed
+func (l *Limiter) Acquire(ctx context.Context, merchantID string) (release func(), err error) {
+	m, err := l.acquireSlot(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+	release = func() { once.Do(func() { l.releaseSlot(m) }) }
+	if err := m.rate.Wait(ctx); err != nil {
+		release()
+		return nil, errors.Wrap(err, "can`t wait for merchant rate limit")
+	}
+	return release, nil
+}
+
+// Stats returns a state of merchantID requests queue
+func (l *Limiter) Stats(merchantID string) LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m, ok := l.merchants[merchantID]
+	if !ok {
+		return LimiterStats{}
+	}
+	return LimiterStats{Active: m.active, Queued: len(m.queue)}
+}
+
+func (l *Limiter) merchant(merchantID string) *merchantLimiter {
+	m, ok := l.merchants[merchantID]
+	if !ok {
+		limit := rate.Inf
+		if l.opts.MinInterval > 0 {
+			limit = rate.Every(l.opts.MinInterval)
+		}
+		m = &merchantLimiter{rate: rate.NewLimiter(limit, 1)}
+		l.merchants[merchantID] = m
+	}
+	return m
+}
+
+// acquireSlot takes one of MaxConcurrent slots of merchantID or waits for it in the queue
+func (l *Limiter) acquireSlot(ctx context.Context, merchantID string) (*merchantLimiter, error) {
+	l.mu.Lock()
+	m := l.merchant(merchantID)
+	if m.active < l.opts.MaxConcurrent && len(m.queue) == 0 {
+		m.active++
+		l.mu.Unlock()
+		return m, nil
+	}
+	ready := make(chan struct{})
+	m.queue = append(m.queue, ready)
+	l.mu.Unlock()
+
+	select {
+	case <-ready:
+		return m, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for i, waiter := range m.queue {
+			if waiter == ready {
+				m.queue = append(m.queue[:i], m.queue[i+1:]...)
+				return nil, errors.Wrap(ctx.Err(), "can`t wait for merchant turn")
+			}
+		}
+		// the slot has been already handed over, pass it to the next waiter
+		l.handOver(m)
+		return nil, errors.Wrap(ctx.Err(), "can`t wait for merchant turn")
+	}
+}
+
+func (l *Limiter) releaseSlot(m *merchantLimiter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handOver(m)
+}
+
+// handOver passes a slot of m to the first waiter or frees it. l.mu must be held
+func (l *Limiter) handOver(m *merchantLimiter) {
+	if len(m.queue) == 0 {
+		m.active--
+		return
+	}
+	close(m.queue[0])
+	m.queue = m.queue[1:]
+}
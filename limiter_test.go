@@ -0,0 +1,106 @@
+package p24
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Run("Serialize", func(t *testing.T) {
+		l := NewLimiter(LimiterOpts{})
+		release, err := l.Acquire(context.Background(), "id")
+		require.NoError(t, err)
+
+		// other merchant is not blocked
+		otherRelease, err := l.Acquire(context.Background(), "other id")
+		require.NoError(t, err)
+		otherRelease()
+
+		order := make(chan int, 3)
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			i := i
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				release, err := l.Acquire(context.Background(), "id")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				order <- i
+				release()
+			}()
+			require.Eventually(t, func() bool { return l.Stats("id").Queued == i+1 }, time.Second, time.Millisecond)
+		}
+		require.Equal(t, LimiterStats{Active: 1, Queued: 3}, l.Stats("id"))
+
+		release()
+		release() // second call is noop
+		wg.Wait()
+		close(order)
+		actual := []int{}
+		for i := range order {
+			actual = append(actual, i)
+		}
+		require.Equal(t, []int{0, 1, 2}, actual)
+		require.Equal(t, LimiterStats{}, l.Stats("id"))
+	})
+
+	t.Run("MaxConcurrent", func(t *testing.T) {
+		l := NewLimiter(LimiterOpts{MaxConcurrent: 2})
+		r1, err := l.Acquire(context.Background(), "id")
+		require.NoError(t, err)
+		r2, err := l.Acquire(context.Background(), "id")
+		require.NoError(t, err)
+		require.Equal(t, LimiterStats{Active: 2}, l.Stats("id"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = l.Acquire(ctx, "id")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, LimiterStats{Active: 2}, l.Stats("id"))
+
+		r1()
+		r2()
+		require.Equal(t, LimiterStats{}, l.Stats("id"))
+	})
+
+	t.Run("MinInterval", func(t *testing.T) {
+		l := NewLimiter(LimiterOpts{MinInterval: 50 * time.Millisecond})
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			release, err := l.Acquire(context.Background(), "id")
+			require.NoError(t, err)
+			release()
+		}
+		require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		_, err := l.Acquire(ctx, "id")
+		require.Error(t, err)
+		require.Equal(t, LimiterStats{}, l.Stats("id"))
+	})
+}
+
+func TestClient_DoContext_Limiter(t *testing.T) {
+	l := NewLimiter(LimiterOpts{})
+	var do DoFunc = func(req *http.Request) (*http.Response, error) {
+		require.Equal(t, LimiterStats{Active: 1}, l.Stats("id"))
+		tr := httptest.NewRecorder()
+		tr.Code = http.StatusInternalServerError
+		return tr.Result(), nil
+	}
+
+	cli := Client{http: do, merchant: Merchant{ID: "id"}, limiter: l}
+	err := cli.DoContext(context.Background(), "http://localhost", http.MethodPost, Request{}, &Response{})
+	require.ErrorContains(t, err, "unexpected http status code 500")
+	require.Equal(t, LimiterStats{}, l.Stats("id"))
+}
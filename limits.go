@@ -0,0 +1,76 @@
+package p24
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+const (
+	// DefaultMaxResponseBytes is a default max size of p24 http response body
+	DefaultMaxResponseBytes int64 = 32 << 20
+	// DefaultMaxXMLDepth is a default max nesting depth of p24 xml response
+	DefaultMaxXMLDepth = 32
+	// DefaultMaxXMLElements is a default max number of elements in p24 xml response
+	DefaultMaxXMLElements = 1 << 20
+)
+
+const (
+	// LimitResponseBytes is a name of http response body size limit
+	LimitResponseBytes = "response bytes"
+	// LimitXMLDepth is a name of xml nesting depth limit
+	LimitXMLDepth = "xml depth"
+	// LimitXMLElements is a name of xml elements count limit
+	LimitXMLElements = "xml elements"
+)
+
+// LimitError reports that p24 http response exceeds one of Client limits
+type LimitError struct {
+	// Limit is a name of exceeded limit
+	Limit string
+	// Max is a value of exceeded limit
+	Max int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s limit %d exceeded", e.Limit, e.Max)
+}
+
+// Is reports whether target is ErrLimitExceeded
+func (e *LimitError) Is(target error) bool { return target == ErrLimitExceeded }
+
+// xmlLimits stores limits of p24 xml response. Zero value means unlimited
+type xmlLimits struct {
+	maxDepth, maxElements int
+}
+
+// limitTokenReader is a xml.TokenReader that returns
+// *LimitError if tokens of the decoder exceed limits
+type limitTokenReader struct {
+	d        *xml.Decoder
+	limits   xmlLimits
+	depth    int
+	elements int
+}
+
+// Token implements xml.TokenReader interface for r
+func (r *limitTokenReader) Token() (xml.Token, error) {
+	token, err := r.d.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token.(type) {
+	case xml.StartElement:
+		r.depth++
+		r.elements++
+		if r.limits.maxDepth > 0 && r.depth > r.limits.maxDepth {
+			return nil, &LimitError{LimitXMLDepth, int64(r.limits.maxDepth)}
+		}
+		if r.limits.maxElements > 0 && r.elements > r.limits.maxElements {
+			return nil, &LimitError{LimitXMLElements, int64(r.limits.maxElements)}
+		}
+	case xml.EndElement:
+		r.depth--
+	}
+	return token, nil
+}
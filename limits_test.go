@@ -0,0 +1,110 @@
+package p24
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseResp_Limits(t *testing.T) {
+	type info struct {
+		A []int `xml:"a"`
+	}
+	cases := []struct {
+		body   string
+		limits xmlLimits
+		limit  string
+	}{
+		{`<response><data><info><a>1</a></info></data></response>`, xmlLimits{maxDepth: 4, maxElements: 4}, ""},
+		{`<response><data><info><a>1</a></info></data></response>`, xmlLimits{maxDepth: 3}, LimitXMLDepth},
+		{`<response><data><info><a>1</a></info></data></response>`, xmlLimits{maxElements: 3}, LimitXMLElements},
+		{`<response><data><info>` + strings.Repeat("<a>1</a>", 100) + `</info></data></response>`, xmlLimits{maxElements: 50}, LimitXMLElements},
+		{`<response><data><info><a>` + strings.Repeat("<b>", 100) + `</a></info></data></response>`, xmlLimits{maxDepth: 10}, LimitXMLDepth},
+		{`<response><data><oper>` + strings.Repeat("<b>", 100) + `</oper></data></response>`, xmlLimits{maxDepth: 10}, LimitXMLDepth},
+		{`<response><data><info><a>1</a></info></data></response>`, xmlLimits{}, ""},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			_, err := parseResp([]byte(c.body), Response{Data: ResponseData{Info: info{}}}, c.limits)
+			if c.limit == "" {
+				require.NoError(t, err)
+				return
+			}
+			var limitErr *LimitError
+			require.True(t, errors.As(err, &limitErr), err)
+			require.Equal(t, c.limit, limitErr.Limit)
+		})
+	}
+}
+
+func TestClient_DoContext_Limits(t *testing.T) {
+	body := []byte(xml.Header + `<response><data><info><test>test</test></info><oper>cmt</oper></data><merchant><id>id</id><signature>ad67cf1c11e0f87bedac2c9bb260e3abf54e9862</signature></merchant></response>`)
+	type info struct {
+		Test string `xml:"test"`
+	}
+
+	cases := []struct {
+		opts  ClientOpts
+		limit string
+	}{
+		{ClientOpts{}, ""},
+		{ClientOpts{MaxResponseBytes: -1, MaxXMLDepth: -1, MaxXMLElements: -1}, ""},
+		{ClientOpts{MaxResponseBytes: int64(len(body))}, ""},
+		{ClientOpts{MaxResponseBytes: int64(len(body)) - 1}, LimitResponseBytes},
+		{ClientOpts{MaxXMLDepth: 3}, LimitXMLDepth},
+		{ClientOpts{MaxXMLElements: 5}, LimitXMLElements},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			c.opts.Merchant = Merchant{"id", "pass"}
+			c.opts.HTTP = DoFunc(func(req *http.Request) (*http.Response, error) {
+				tr := httptest.NewRecorder()
+				_, _ = tr.Write(body)
+				resp := tr.Result()
+				resp.ContentLength = -1 // check limit of body reader
+				return resp, nil
+			})
+			cli, err := NewClient(c.opts)
+			require.NoError(t, err)
+
+			resp := Response{Data: ResponseData{Info: info{}}}
+			err = cli.DoContext(context.Background(), "http://localhost", http.MethodPost, Request{}, &resp)
+			if c.limit == "" {
+				require.NoError(t, err)
+				require.Equal(t, info{"test"}, resp.Data.Info)
+				return
+			}
+			var limitErr *LimitError
+			require.True(t, errors.As(err, &limitErr), err)
+			require.Equal(t, c.limit, limitErr.Limit)
+			require.False(t, IsRetryable(err))
+		})
+	}
+
+	t.Run("ContentLength", func(t *testing.T) {
+		cli, err := NewClient(ClientOpts{
+			MaxResponseBytes: 10,
+			HTTP: DoFunc(func(req *http.Request) (*http.Response, error) {
+				tr := httptest.NewRecorder()
+				_, _ = tr.Write(body)
+				resp := tr.Result()
+				resp.ContentLength = int64(len(body))
+				return resp, nil
+			}),
+		})
+		require.NoError(t, err)
+		err = cli.DoContext(context.Background(), "http://localhost", http.MethodPost, Request{}, &Response{})
+		require.ErrorContains(t, err, "response bytes limit 10 exceeded")
+	})
+}
@@ -0,0 +1,86 @@
+package p24
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Level is a severity of log event
+type Level int
+
+// Log levels, values are the same as log/slog ones
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+// String returns upper case name of l
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+}
+
+// Field is a key/value pair of log event
+type Field struct {
+	Value interface{}
+	Key   string
+}
+
+// F returns Field with given key and value
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// LogfAdapter adapts printf-style Logger to StructuredLogger.
+// Events are formatted like "[WARN] msg key1=value1 key2=value2"
+type LogfAdapter struct {
+	Logger Logger
+	// MinLevel is a min level of events passed to Logger
+	MinLevel Level
+}
+
+// Log implements StructuredLogger interface for a
+func (a LogfAdapter) Log(_ context.Context, level Level, msg string, fields ...Field) {
+	if level < a.MinLevel {
+		return
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "[%s] %s", level, msg)
+	for _, f := range fields {
+		fmt.Fprintf(b, " %s=%v", f.Key, f.Value)
+	}
+	a.Logger.Logf("%s\n", b.String())
+}
+
+// nopLogger is a StructuredLogger that discards all events
+var nopLogger StructuredLogger = StructuredLogFunc(func(context.Context, Level, string, ...Field) {})
+
+// logger returns Client StructuredLogger or nopLogger if it is nil
+func (c *Client) logger() StructuredLogger {
+	if c.log == nil {
+		return nopLogger
+	}
+	return c.log
+}
+
+// logEvent passes event to Client logger with redacted field values
+func (c *Client) logEvent(ctx context.Context, level Level, msg string, fields ...Field) {
+	for i := range fields {
+		fields[i].Value = c.redact.value(fields[i].Value)
+	}
+	c.logger().Log(ctx, level, msg, fields...)
+}
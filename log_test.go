@@ -0,0 +1,78 @@
+package p24
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogfAdapter(t *testing.T) {
+	var out []string
+	log := LogfAdapter{
+		Logger: LogFunc(func(format string, args ...interface{}) {
+			out = append(out, fmt.Sprintf(format, args...))
+		}),
+		MinLevel: LevelInfo,
+	}
+
+	log.Log(context.Background(), LevelDebug, "debug msg")
+	log.Log(context.Background(), LevelInfo, "info msg")
+	log.Log(context.Background(), LevelError, "error msg", F("a", 1), F("b", "str"))
+	require.Equal(t, []string{"[INFO] info msg\n", "[ERROR] error msg a=1 b=str\n"}, out)
+	require.Equal(t, "LEVEL(1)", Level(1).String())
+}
+
+func TestClient_DoContext_Log(t *testing.T) {
+	type event struct {
+		fields map[string]interface{}
+		level  Level
+		msg    string
+	}
+	var events []event
+	log := StructuredLogFunc(func(ctx context.Context, level Level, msg string, fields ...Field) {
+		e := event{level: level, msg: msg, fields: map[string]interface{}{}}
+		for _, f := range fields {
+			e.fields[f.Key] = f.Value
+		}
+		events = append(events, e)
+	})
+
+	codes := []int{500, 200}
+	body := []byte(xml.Header + `<response><data><info>invalid card 1234567890123456</info><oper>cmt</oper></data></response>`)
+	cli, err := NewClient(ClientOpts{
+		StructuredLog: log,
+		Retry:         RetryPolicy{MaxAttempts: 2},
+		HTTP: DoFunc(func(req *http.Request) (*http.Response, error) {
+			tr := httptest.NewRecorder()
+			_, _ = tr.Write(body)
+			tr.Code, codes = codes[0], codes[1:]
+			return tr.Result(), nil
+		}),
+	})
+	require.NoError(t, err)
+
+	err = cli.DoContext(context.Background(), cli.endpointURL(EndpointStatements), http.MethodPost, Request{}, &Response{})
+	require.EqualError(t, err, "xml response with error: invalid card 123456******3456")
+
+	msgs := make([]string, 0, len(events))
+	for _, e := range events {
+		msgs = append(msgs, e.level.String()+" "+e.msg)
+		require.Equal(t, EndpointStatements, e.fields["endpoint"])
+	}
+	require.Equal(t, []string{
+		"DEBUG p24 request started",
+		"DEBUG p24 response received",
+		"INFO p24 request retry",
+		"DEBUG p24 request started",
+		"DEBUG p24 response received",
+		"DEBUG p24 response rejected",
+	}, msgs)
+	require.Equal(t, 500, events[1].fields["status"])
+	require.Equal(t, 2, events[3].fields["attempt"])
+	require.Equal(t, "xml response with error: invalid card 123456******3456", events[5].fields["err"])
+}
@@ -5,7 +5,11 @@ import (
 	"crypto/md5"
 	// nolint:gosec // p24 api require it
 	"crypto/sha1"
+	"crypto/subtle"
 	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
 
 	"github.com/pkg/errors"
 )
@@ -44,8 +48,64 @@ func (m Merchant) Sign(data []byte) MerchantSign {
 
 // VerifySign returns an error if dataSign not from merchant.Sign(data)
 func (m Merchant) VerifySign(data []byte, dataSign MerchantSign) error {
-	if dataSign != m.Sign(data) {
+	return verifySign(m.Sign(data), dataSign)
+}
+
+// verifySign returns an error if dataSign is not equal to expected one.
+// Signatures are compared in constant time
+func verifySign(expected, dataSign MerchantSign) error {
+	idOK := subtle.ConstantTimeCompare([]byte(expected.ID), []byte(dataSign.ID))
+	signOK := subtle.ConstantTimeCompare([]byte(expected.Sign), []byte(dataSign.Sign))
+	if idOK&signOK != 1 {
 		return errors.New("invalid signature")
 	}
 	return nil
 }
+
+// redactedPass returns masked m.Pass or empty string if it is not set
+func (m Merchant) redactedPass() string {
+	if m.Pass == "" {
+		return ""
+	}
+	return redacted
+}
+
+// String returns m with masked password
+func (m Merchant) String() string {
+	return fmt.Sprintf("{%s %s}", m.ID, m.redactedPass())
+}
+
+// GoString returns Go syntax representation of m with masked password
+func (m Merchant) GoString() string {
+	return fmt.Sprintf("p24.Merchant{ID:%q, Pass:%q}", m.ID, m.redactedPass())
+}
+
+// Format implements fmt.Formatter for m, the password is masked for all verbs
+func (m Merchant) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('#'):
+		_, _ = io.WriteString(s, m.GoString())
+	case verb == 'v' && s.Flag('+'):
+		fmt.Fprintf(s, "{ID:%s Pass:%s}", m.ID, m.redactedPass())
+	case verb == 'v' || verb == 's':
+		_, _ = io.WriteString(s, m.String())
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", m.String())
+	default:
+		fmt.Fprintf(s, "%%!%c(p24.Merchant=%s)", verb, m.String())
+	}
+}
+
+// MarshalJSON implements json.Marshaler for m, the password is masked
+func (m Merchant) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ID   string `json:"id"`
+		Pass string `json:"pass"`
+	}{m.ID, m.redactedPass()})
+}
+
+// MarshalText implements encoding.TextMarshaler for m, the password is masked.
+// It is used by encoding/xml as well
+func (m Merchant) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
@@ -1,8 +1,14 @@
 package p24
 
 import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -51,3 +57,88 @@ func Test_Merchant(t *testing.T) {
 		}
 	})
 }
+
+func TestMerchant_Format(t *testing.T) {
+	m := Merchant{"id", "pass"}
+	cases := []struct {
+		format   string
+		expected string
+	}{
+		{"%v", "{id [REDACTED]}"},
+		{"%s", "{id [REDACTED]}"},
+		{"%+v", "{ID:id Pass:[REDACTED]}"},
+		{"%#v", `p24.Merchant{ID:"id", Pass:"[REDACTED]"}`},
+		{"%q", `"{id [REDACTED]}"`},
+		{"%x", "%!x(p24.Merchant={id [REDACTED]})"},
+		{"%v", "{id [REDACTED]}"},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			v := interface{}(m)
+			if i == len(cases)-1 {
+				v = &m
+			}
+			require.Equal(t, c.expected, fmt.Sprintf(c.format, v))
+		})
+	}
+	require.Equal(t, "{id }", Merchant{ID: "id"}.String())
+
+	b, err := json.Marshal(m)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":"id","pass":"[REDACTED]"}`, string(b))
+}
+
+// TestMerchant_PassLeak checks that no exported path prints merchant password
+func TestMerchant_PassLeak(t *testing.T) {
+	const pass = "secret-password"
+	m := Merchant{"id", pass}
+	reloading := NewReloadingCredentials(m, time.Minute)
+	_, err := reloading.Credentials(context.Background())
+	require.NoError(t, err)
+	cli, err := NewClient(ClientOpts{Merchant: m, Signer: NewCredentialsSigner(reloading)})
+	require.NoError(t, err)
+
+	values := []interface{}{
+		m, &m,
+		ClientOpts{Merchant: m, Signer: m}, &ClientOpts{Merchant: m, Signer: NewCredentialsSigner(m)},
+		cli, *cli,
+		NewCredentialsSigner(m), NewCredentialsSigner(reloading), reloading,
+		[]Merchant{m}, map[string]Merchant{"m": m}, struct{ M Merchant }{m},
+	}
+	formats := []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%X", "%d", "%T", "%100v"}
+	for i, v := range values {
+		for _, format := range formats {
+			require.NotContains(t, fmt.Sprintf(format, v), pass, "value %d format %s", i, format)
+		}
+		require.NotContains(t, fmt.Sprint(v), pass, "value %d", i)
+	}
+
+	type holder struct {
+		M Merchant
+	}
+	marshalers := map[string]func(v interface{}) ([]byte, error){
+		"json": json.Marshal,
+		"xml":  xml.Marshal,
+		"text": func(v interface{}) ([]byte, error) {
+			return v.(encoding.TextMarshaler).MarshalText()
+		},
+	}
+	for name, marshal := range marshalers {
+		for _, v := range []interface{}{m, &m, holder{m}, &holder{m}, []Merchant{m}} {
+			if _, ok := v.(encoding.TextMarshaler); name == "text" && !ok {
+				continue
+			}
+			b, err := marshal(v)
+			require.NoError(t, err, name)
+			require.NotContains(t, string(b), pass, name)
+		}
+	}
+
+	// a merchant in a log event is masked
+	var out string
+	log := LogfAdapter{Logger: LogFunc(func(format string, v ...interface{}) { out += fmt.Sprintf(format, v...) }), MinLevel: LevelDebug}
+	(&Client{log: log}).logEvent(context.Background(), LevelInfo, "msg", F("merchant", m))
+	require.Equal(t, "[INFO] msg merchant={id [REDACTED]}\n", out)
+}
@@ -0,0 +1,311 @@
+package p24
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Metric names reported by Client
+const (
+	// MetricRequestsTotal counts http attempts by endpoint, status and error_kind labels.
+	// Attempts rejected by circuit breaker or limiter before sending a request have "rejected" status
+	// and "circuit_open" or "limiter" error_kind
+	MetricRequestsTotal = "p24_requests_total"
+	// MetricRequestDuration observes http attempt latency in seconds by endpoint label
+	MetricRequestDuration = "p24_request_duration_seconds"
+	// MetricRetriesTotal counts retries by endpoint label
+	MetricRetriesTotal = "p24_retries_total"
+	// MetricSignatureFailuresTotal counts responses with invalid signature by endpoint label
+	MetricSignatureFailuresTotal = "p24_signature_failures_total"
+)
+
+// Error kinds used as error_kind metric label
+const (
+	errKindTransport  = "transport"
+	errKindHTTPStatus = "http_status"
+	errKindLimit      = "limit"
+	errKindContent    = "content"
+	errKindP24        = "p24"
+	errKindSignature  = "signature"
+	errKindDecode     = "decode"
+	errKindMismatch   = "mismatch"
+	errKindCanceled   = "canceled"
+	errKindOther      = "other"
+
+	errKindCircuitOpen = "circuit_open"
+	errKindLimiter     = "limiter"
+)
+
+// statusRejected is a status label of attempts rejected before sending a request
+const statusRejected = "rejected"
+
+// Labels is a set of metric label names and values
+type Labels map[string]string
+
+// Metrics defines minimal metrics collector interface
+type Metrics interface {
+	// IncCounter increments counter with given name and labels by 1
+	IncCounter(name string, labels Labels)
+	// ObserveHistogram adds value to histogram with given name and labels
+	ObserveHistogram(name string, labels Labels, value float64)
+}
+
+// errorKind returns a kind of p24 api call error or empty string if err is nil
+func errorKind(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrLimitExceeded):
+		return errKindLimit
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return errKindCanceled
+	case errors.Is(err, ErrTransport):
+		return errKindTransport
+	case errors.Is(err, ErrHTTPStatus):
+		return errKindHTTPStatus
+	case errors.Is(err, ErrInvalidContent):
+		return errKindContent
+	case errors.Is(err, ErrP24):
+		return errKindP24
+	case errors.Is(err, ErrInvalidSignature):
+		return errKindSignature
+	case errors.Is(err, ErrDecode):
+		return errKindDecode
+	case errors.Is(err, ErrResponseMismatch):
+		return errKindMismatch
+	default:
+		return errKindOther
+	}
+}
+
+// observeAttempt reports metrics of a http attempt to Client metrics
+func (c *Client) observeAttempt(endpoint Endpoint, status int, seconds float64, err error) {
+	if c.metrics == nil {
+		return
+	}
+
+	kind := errorKind(err)
+	c.metrics.IncCounter(MetricRequestsTotal, Labels{
+		"endpoint":   string(endpoint),
+		"status":     strconv.Itoa(status),
+		"error_kind": kind,
+	})
+	c.metrics.ObserveHistogram(MetricRequestDuration, Labels{"endpoint": string(endpoint)}, seconds)
+	if kind == errKindSignature {
+		c.metrics.IncCounter(MetricSignatureFailuresTotal, Labels{"endpoint": string(endpoint)})
+	}
+}
+
+// observeRejected reports an attempt rejected with kind before sending a request to Client metrics
+func (c *Client) observeRejected(endpoint Endpoint, kind string) {
+	if c.metrics != nil {
+		c.metrics.IncCounter(MetricRequestsTotal, Labels{
+			"endpoint":   string(endpoint),
+			"status":     statusRejected,
+			"error_kind": kind,
+		})
+	}
+}
+
+// observeRetry reports a retry to Client metrics
+func (c *Client) observeRetry(endpoint Endpoint) {
+	if c.metrics != nil {
+		c.metrics.IncCounter(MetricRetriesTotal, Labels{"endpoint": string(endpoint)})
+	}
+}
+
+// DefaultBuckets are default MemoryMetrics histogram upper bounds in seconds
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// MemoryMetrics is an in-memory Metrics implementation
+// that can render collected metrics in Prometheus text exposition format.
+// It is safe for concurrent use
+type MemoryMetrics struct {
+	counters   map[string]memSeries
+	histograms map[string]memSeries
+	buckets    []float64
+	mu         sync.Mutex
+}
+
+// memSeries stores metric values by Labels.String() keys
+type memSeries map[string]*memValue
+
+type memValue struct {
+	labels Labels
+	counts []uint64
+	value  float64
+	sum    float64
+	count  uint64
+}
+
+// NewMemoryMetrics returns MemoryMetrics with given histogram buckets.
+// DefaultBuckets are used if buckets is empty
+func NewMemoryMetrics(buckets ...float64) *MemoryMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &MemoryMetrics{
+		counters:   map[string]memSeries{},
+		histograms: map[string]memSeries{},
+		buckets:    buckets,
+	}
+}
+
+// IncCounter implements Metrics interface for m
+func (m *MemoryMetrics) IncCounter(name string, labels Labels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.value(m.counters, name, labels).value++
+}
+
+// ObserveHistogram implements Metrics interface for m
+func (m *MemoryMetrics) ObserveHistogram(name string, labels Labels, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h := m.value(m.histograms, name, labels)
+	if h.counts == nil {
+		h.counts = make([]uint64, len(m.buckets))
+	}
+	for i, bound := range m.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// value returns a value of metric with given name and labels, m.mu must be held
+func (m *MemoryMetrics) value(metrics map[string]memSeries, name string, labels Labels) *memValue {
+	series, ok := metrics[name]
+	if !ok {
+		series = memSeries{}
+		metrics[name] = series
+	}
+	key := labels.String()
+	v, ok := series[key]
+	if !ok {
+		v = &memValue{labels: labels.with()}
+		series[key] = v
+	}
+	return v
+}
+
+// Counter returns value of counter with given name and labels
+func (m *MemoryMetrics) Counter(name string, labels Labels) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if v, ok := m.counters[name][labels.String()]; ok {
+		return v.value
+	}
+	return 0
+}
+
+// HistogramCount returns a number of observations of histogram with given name and labels
+func (m *MemoryMetrics) HistogramCount(name string, labels Labels) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if v, ok := m.histograms[name][labels.String()]; ok {
+		return v.count
+	}
+	return 0
+}
+
+// WritePrometheus writes collected metrics to w in Prometheus text exposition format
+func (m *MemoryMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, name := range sortedNames(m.counters) {
+		fmt.Fprintf(bw, "# TYPE %s counter\n", name)
+		for _, v := range m.counters[name].sorted() {
+			fmt.Fprintf(bw, "%s%s %s\n", name, v.labels, formatFloat(v.value))
+		}
+	}
+	for _, name := range sortedNames(m.histograms) {
+		fmt.Fprintf(bw, "# TYPE %s histogram\n", name)
+		for _, v := range m.histograms[name].sorted() {
+			for i, bound := range m.buckets {
+				fmt.Fprintf(bw, "%s_bucket%s %d\n", name, v.labels.with("le", formatFloat(bound)), v.counts[i])
+			}
+			fmt.Fprintf(bw, "%s_bucket%s %d\n", name, v.labels.with("le", "+Inf"), v.count)
+			fmt.Fprintf(bw, "%s_sum%s %s\n", name, v.labels, formatFloat(v.sum))
+			fmt.Fprintf(bw, "%s_count%s %d\n", name, v.labels, v.count)
+		}
+	}
+	return bw.Flush()
+}
+
+// sorted returns values of s sorted by labels
+func (s memSeries) sorted() []*memValue {
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]*memValue, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, s[key])
+	}
+	return values
+}
+
+func sortedNames(metrics map[string]memSeries) []string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String returns Prometheus representation of l like {a="1",b="2"}
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, name+`="`+replacer.Replace(l[name])+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// with returns a copy of l with additional name/value pairs
+func (l Labels) with(nameValues ...string) Labels {
+	cp := make(Labels, len(l)+len(nameValues)/2)
+	for k, v := range l {
+		cp[k] = v
+	}
+	for i := 0; i+1 < len(nameValues); i += 2 {
+		cp[nameValues[i]] = nameValues[i+1]
+	}
+	return cp
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,153 @@
+package p24
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryMetrics(t *testing.T) {
+	m := NewMemoryMetrics(1, 0.5)
+	m.IncCounter("b_total", Labels{"x": "1"})
+	m.IncCounter("b_total", Labels{"x": "1"})
+	m.IncCounter("b_total", Labels{"x": `q"\` + "\n"})
+	m.IncCounter("a_total", nil)
+	m.ObserveHistogram("latency", Labels{"x": "1"}, 0.3)
+	m.ObserveHistogram("latency", Labels{"x": "1"}, 0.75)
+	m.ObserveHistogram("latency", Labels{"x": "1"}, 2)
+
+	require.Equal(t, float64(2), m.Counter("b_total", Labels{"x": "1"}))
+	require.Equal(t, float64(0), m.Counter("b_total", Labels{"x": "2"}))
+	require.Equal(t, uint64(3), m.HistogramCount("latency", Labels{"x": "1"}))
+	require.Equal(t, uint64(0), m.HistogramCount("unknown", nil))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, m.WritePrometheus(buf))
+	require.Equal(t, `# TYPE a_total counter
+a_total 1
+# TYPE b_total counter
+b_total{x="1"} 2
+b_total{x="q\"\\\n"} 1
+# TYPE latency histogram
+latency_bucket{le="0.5",x="1"} 1
+latency_bucket{le="1",x="1"} 2
+latency_bucket{le="+Inf",x="1"} 3
+latency_sum{x="1"} 3.05
+latency_count{x="1"} 3
+`, buf.String())
+}
+
+func Test_errorKind(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind string
+	}{
+		{nil, ""},
+		{newError(&transportError{errors.New("reset")}, "", "", nil, nil), errKindTransport},
+		{newError(&transportError{context.Canceled}, "", "", nil, nil), errKindCanceled},
+		{newError(&statusCodeError{500}, "", "", nil, nil), errKindHTTPStatus},
+		{newError(errors.Wrap(&LimitError{LimitXMLDepth, 1}, "unexpected xml response content"), "", "", nil, nil), errKindLimit},
+		{newError(&classError{errors.New("invalid signature"), ErrInvalidSignature}, "", "", nil, nil), errKindSignature},
+		{errors.New("other"), errKindOther},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require.Equal(t, c.kind, errorKind(c.err))
+		})
+	}
+}
+
+func TestClient_DoContext_Metrics(t *testing.T) {
+	bodies := [][]byte{
+		nil,
+		[]byte(xml.Header + `<response><data><info><test>test</test></info><oper>cmt</oper></data><merchant><id>id</id><signature>bad</signature></merchant></response>`),
+		[]byte(xml.Header + `<response><data><info><test>test</test></info><oper>cmt</oper></data><merchant><id>id</id><signature>ad67cf1c11e0f87bedac2c9bb260e3abf54e9862</signature></merchant></response>`),
+	}
+	codes := []int{503, 200, 200}
+	m := NewMemoryMetrics()
+	cli, err := NewClient(ClientOpts{
+		Merchant: Merchant{"id", "pass"},
+		Metrics:  m,
+		Retry:    RetryPolicy{MaxAttempts: 3},
+		HTTP: DoFunc(func(req *http.Request) (*http.Response, error) {
+			tr := httptest.NewRecorder()
+			_, _ = tr.Write(bodies[0])
+			tr.Code = codes[0]
+			bodies, codes = bodies[1:], codes[1:]
+			return tr.Result(), nil
+		}),
+	})
+	require.NoError(t, err)
+
+	type info struct {
+		Test string `xml:"test"`
+	}
+	url := cli.endpointURL(EndpointCardBalance)
+	err = cli.DoContext(context.Background(), url, http.MethodPost, Request{}, &Response{Data: ResponseData{Info: info{}}})
+	require.ErrorContains(t, err, "invalid signature")
+
+	err = cli.DoContext(context.Background(), url, http.MethodPost, Request{}, &Response{Data: ResponseData{Info: info{}}})
+	require.NoError(t, err)
+
+	labels := func(status, kind string) Labels {
+		return Labels{"endpoint": "balance", "status": status, "error_kind": kind}
+	}
+	require.Equal(t, float64(1), m.Counter(MetricRequestsTotal, labels("503", errKindHTTPStatus)))
+	require.Equal(t, float64(1), m.Counter(MetricRequestsTotal, labels("200", errKindSignature)))
+	require.Equal(t, float64(1), m.Counter(MetricRequestsTotal, labels("200", "")))
+	require.Equal(t, float64(1), m.Counter(MetricRetriesTotal, Labels{"endpoint": "balance"}))
+	require.Equal(t, float64(1), m.Counter(MetricSignatureFailuresTotal, Labels{"endpoint": "balance"}))
+	require.Equal(t, uint64(3), m.HistogramCount(MetricRequestDuration, Labels{"endpoint": "balance"}))
+}
+
+func TestClient_DoContext_MetricsRejected(t *testing.T) {
+	m := NewMemoryMetrics()
+	badGateway := DoFunc(func(req *http.Request) (*http.Response, error) {
+		tr := httptest.NewRecorder()
+		tr.Code = http.StatusBadGateway
+		return tr.Result(), nil
+	})
+	url := DefaultBaseURL + endpointPaths[EndpointStatements]
+
+	limiter := NewLimiter(LimiterOpts{})
+	cli, err := NewClient(ClientOpts{Merchant: Merchant{"id", "pass"}, Metrics: m, Limiter: limiter, HTTP: badGateway})
+	require.NoError(t, err)
+	// the merchant turn is taken by another call
+	release, err := limiter.Acquire(context.Background(), "id")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = cli.DoContext(ctx, url, http.MethodPost, Request{}, &Response{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	release()
+
+	cli, err = NewClient(ClientOpts{
+		Merchant: Merchant{"id", "pass"},
+		Metrics:  m,
+		Breaker:  &BreakerOpts{MinRequests: 1, Cooldown: time.Hour},
+		HTTP:     badGateway,
+	})
+	require.NoError(t, err)
+	err = cli.DoContext(context.Background(), url, http.MethodPost, Request{}, &Response{})
+	require.ErrorIs(t, err, ErrHTTPStatus)
+	err = cli.DoContext(context.Background(), url, http.MethodPost, Request{}, &Response{})
+	require.ErrorIs(t, err, ErrCircuitOpen)
+
+	labels := func(status, kind string) Labels {
+		return Labels{"endpoint": "statements", "status": status, "error_kind": kind}
+	}
+	require.Equal(t, float64(1), m.Counter(MetricRequestsTotal, labels(statusRejected, errKindLimiter)))
+	require.Equal(t, float64(1), m.Counter(MetricRequestsTotal, labels("502", errKindHTTPStatus)))
+	require.Equal(t, float64(1), m.Counter(MetricRequestsTotal, labels(statusRejected, errKindCircuitOpen)))
+	require.Equal(t, uint64(1), m.HistogramCount(MetricRequestDuration, Labels{"endpoint": "statements"}))
+}
@@ -0,0 +1,37 @@
+package p24
+
+import "context"
+
+// Call describes a p24 api call passed through Middleware chain
+type Call struct {
+	// Endpoint is a name of called endpoint. It is empty if URL is not a known endpoint url
+	Endpoint Endpoint
+	URL      string
+	Method   string
+	Req      Request
+
+	// check is an endpoint specific check of the verified response, see Client.checkStrict.
+	// It is kept by middlewares that pass a modified copy of the call
+	check responseCheck
+	// data receives raw verified '<data>' content of the response if not nil
+	data *[]byte
+}
+
+// Handler performs a p24 api call and unmarshal response to resp.
+// A returned error is usually *Error
+type Handler func(ctx context.Context, call Call, resp *Response) error
+
+// Middleware wraps Handler to extend p24 api call behavior
+// like logging, metrics, caching, auditing or fault injection
+type Middleware func(next Handler) Handler
+
+// Chain returns Middleware that wraps Handler by mws.
+// The first Middleware is the outermost one
+func Chain(mws ...Middleware) Middleware {
+	return func(next Handler) Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			next = mws[i](next)
+		}
+		return next
+	}
+}
@@ -0,0 +1,106 @@
+package p24
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChain(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, call Call, resp *Response) error {
+				order = append(order, name+" before")
+				err := next(ctx, call, resp)
+				order = append(order, name+" after")
+				return err
+			}
+		}
+	}
+	h := Chain(mw("1"), mw("2"))(func(ctx context.Context, call Call, resp *Response) error {
+		order = append(order, "handler")
+		return nil
+	})
+
+	require.NoError(t, h(context.Background(), Call{}, &Response{}))
+	require.Equal(t, []string{"1 before", "2 before", "handler", "2 after", "1 after"}, order)
+
+	order = nil
+	require.NoError(t, Chain()(h)(context.Background(), Call{}, &Response{}))
+	require.Len(t, order, 5)
+}
+
+func TestClient_DoContext_Middlewares(t *testing.T) {
+	body := []byte(xml.Header + `<response><data><info><test>test</test></info><oper>cmt</oper></data><merchant><id>id</id><signature>ad67cf1c11e0f87bedac2c9bb260e3abf54e9862</signature></merchant></response>`)
+	type info struct {
+		Test string `xml:"test"`
+	}
+	httpCalls := 0
+	var do DoFunc = func(req *http.Request) (*http.Response, error) {
+		httpCalls++
+		tr := httptest.NewRecorder()
+		_, _ = tr.Write(body)
+		return tr.Result(), nil
+	}
+
+	var calls []Call
+	audit := func(next Handler) Handler {
+		return func(ctx context.Context, call Call, resp *Response) error {
+			calls = append(calls, call)
+			return next(ctx, call, resp)
+		}
+	}
+	cache := map[string]Response{}
+	caching := func(next Handler) Handler {
+		return func(ctx context.Context, call Call, resp *Response) error {
+			if cached, ok := cache[call.URL]; ok {
+				*resp = cached
+				return nil
+			}
+			if err := next(ctx, call, resp); err != nil {
+				return err
+			}
+			cache[call.URL] = *resp
+			return nil
+		}
+	}
+	faultErr := errors.New("injected fault")
+	fault := func(next Handler) Handler {
+		return func(ctx context.Context, call Call, resp *Response) error {
+			if call.Endpoint == EndpointCardBalance {
+				return faultErr
+			}
+			return next(ctx, call, resp)
+		}
+	}
+
+	cli, err := NewClient(ClientOpts{
+		HTTP:        do,
+		Merchant:    Merchant{"id", "pass"},
+		Middlewares: []Middleware{audit, caching, fault},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		resp := Response{Data: ResponseData{Info: info{}}}
+		require.NoError(t, cli.DoContext(context.Background(), cli.endpointURL(EndpointStatements), http.MethodPost, Request{}, &resp))
+		require.Equal(t, info{"test"}, resp.Data.Info)
+	}
+	require.Equal(t, 1, httpCalls)
+
+	err = cli.DoContext(context.Background(), cli.endpointURL(EndpointCardBalance), http.MethodPost, Request{}, &Response{})
+	require.ErrorIs(t, err, faultErr)
+	require.Equal(t, 1, httpCalls)
+
+	require.Len(t, calls, 3)
+	require.Equal(t, EndpointStatements, calls[0].Endpoint)
+	require.Equal(t, "https://api.privatbank.ua/p24api/rest_fiz", calls[0].URL)
+	require.Equal(t, http.MethodPost, calls[0].Method)
+	require.Equal(t, EndpointCardBalance, calls[2].Endpoint)
+}
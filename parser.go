@@ -0,0 +1,293 @@
+package p24
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// parsedResp is a result of single pass parsing of p24 http response body
+type parsedResp struct {
+	// respErr is a p24 error reported by response if any
+	respErr error
+
+	// decodeErr is an error of decoding '<info>' payload if any
+	decodeErr error
+
+	// data is the raw content of '<response><data>' tag that is signed by p24
+	data []byte
+
+	// dataStart is an offset of data in the body
+	dataStart int
+
+	resp Response
+}
+
+// parseResp tokenizes p24 http response body once. It detects all p24 error shapes,
+// captures raw '<data>' content for signature verification and decodes '<info>' payload
+// to a new value of the resp.Data.Info type. It returns an error if body is not a valid p24 response
+// or *LimitError if body exceeds limits
+func parseResp(body []byte, resp Response, limits xmlLimits) (*parsedResp, error) {
+	return newRespParser(body, limits).parse(resp.Data.Info)
+}
+
+type respParser struct {
+	// d decodes tokens of raw with respect to limits
+	d *xml.Decoder
+	// raw tokenizes body, it is used to get offsets of tokens
+	raw  *xml.Decoder
+	body []byte
+}
+
+func newRespParser(body []byte, limits xmlLimits) *respParser {
+	raw := xml.NewDecoder(bytes.NewReader(body))
+	return &respParser{
+		d:    xml.NewTokenDecoder(&limitTokenReader{d: raw, limits: limits}),
+		raw:  raw,
+		body: body,
+	}
+}
+
+func (p *respParser) parse(info interface{}) (*parsedResp, error) {
+	root, err := p.nextStart()
+	if err != nil {
+		return nil, err
+	}
+
+	switch root.Name.Local {
+	case "error":
+		// like: <error>For input string: "some input"</error>
+		var msg string
+		if err := p.d.DecodeElement(&msg, &root); err != nil {
+			return nil, err
+		}
+		if msg == "" {
+			return nil, errors.New("empty error")
+		}
+		return &parsedResp{respErr: &respErr{msg}}, nil
+	case "response":
+		return p.parseResponse(root, info)
+	default:
+		return nil, errors.Errorf("unexpected root element <%s>", root.Name.Local)
+	}
+}
+
+func (p *respParser) parseResponse(root xml.StartElement, info interface{}) (*parsedResp, error) {
+	parsed := &parsedResp{resp: Response{XMLName: root.Name}}
+	for _, attr := range root.Attr {
+		if attr.Name.Local == "version" {
+			parsed.resp.Version = attr.Value
+		}
+	}
+
+	infoFound := false
+	data, dataStart, err := p.walkRoot(func(t xml.StartElement) error {
+		if t.Name.Local == "merchant" {
+			return p.d.DecodeElement(&parsed.resp.MerchantSign, &t)
+		}
+		return p.d.Skip()
+	}, func(t xml.StartElement) error {
+		switch t.Name.Local {
+		case "error":
+			// like: <data><error message ="invalid signature" /></data>
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "message" && attr.Value != "" && parsed.respErr == nil {
+					parsed.respErr = &respDataErr{attr.Value}
+				}
+			}
+			return p.d.Skip()
+		case "oper":
+			return p.d.DecodeElement(&parsed.resp.Data.Oper, &t)
+		case "info":
+			infoFound = true
+			return p.parseInfo(parsed, t, info)
+		default:
+			return p.d.Skip()
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	parsed.data, parsed.dataStart = data, dataStart
+	if !infoFound && parsed.respErr == nil && parsed.decodeErr == nil {
+		parsed.decodeErr = errors.New("empty info")
+	}
+	return parsed, nil
+}
+
+// walkRoot reads children of the root element which start tag is already consumed.
+// It calls dataChild for child elements of the single '<data>' element and child for other
+// child elements of the root, both of them must consume the element.
+// It returns raw content of '<data>' element that is signed by p24 and its offset in the body
+func (p *respParser) walkRoot(child, dataChild func(t xml.StartElement) error) ([]byte, int, error) {
+	var (
+		data      []byte
+		dataStart int
+		dataFound bool
+	)
+	for {
+		token, err := p.d.Token()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "data" {
+				if err := child(t); err != nil {
+					return nil, 0, err
+				}
+				continue
+			}
+			if dataFound {
+				return nil, 0, errors.New("invalid '<data>' tag: duplicated")
+			}
+			dataFound = true
+			if data, dataStart, err = p.dataContent(dataChild); err != nil {
+				return nil, 0, err
+			}
+		case xml.EndElement:
+			if !dataFound {
+				return nil, 0, errors.New("invalid '<data>' tag: not found")
+			}
+			return data, dataStart, nil
+		}
+	}
+}
+
+// dataContent reads content of '<data>' element which start tag is already consumed.
+// It calls child for its child elements and returns the raw content and its offset in the body
+func (p *respParser) dataContent(child func(t xml.StartElement) error) ([]byte, int, error) {
+	start := p.raw.InputOffset()
+	for {
+		end := p.raw.InputOffset()
+		token, err := p.d.Token()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		switch t := token.(type) {
+		case xml.EndElement:
+			return p.body[start:end], int(start), nil
+		case xml.StartElement:
+			if err := child(t); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+}
+
+// parseInfo detects '<info>an error msg</info>' p24 error or decodes info payload
+func (p *respParser) parseInfo(parsed *parsedResp, start xml.StartElement, info interface{}) error {
+	// buffer leading char data tokens to find out whether info contains child elements
+	r := &elemTokenReader{d: p.d, buf: []xml.Token{start.Copy()}}
+	text := []byte{}
+	for {
+		token, err := p.d.Token()
+		if err != nil {
+			return err
+		}
+		r.buf = append(r.buf, xml.CopyToken(token))
+
+		if cd, ok := token.(xml.CharData); ok {
+			text = append(text, cd...)
+			continue
+		}
+		// text only info is an error message
+		if _, ok := token.(xml.EndElement); ok && len(bytes.TrimSpace(text)) != 0 {
+			if parsed.respErr == nil {
+				parsed.respErr = &respDataInfoErr{string(text)}
+			}
+			return nil
+		}
+		break
+	}
+
+	if info == nil {
+		parsed.decodeErr = errors.New("empty info")
+		return r.skip()
+	}
+
+	tmpInfo := reflect.New(reflect.TypeOf(info))
+	if err := xml.NewTokenDecoder(r).Decode(tmpInfo.Interface()); err != nil {
+		var (
+			syntaxErr *xml.SyntaxError
+			limitErr  *LimitError
+		)
+		if errors.As(err, &syntaxErr) || errors.As(err, &limitErr) {
+			return err
+		}
+		parsed.decodeErr = err
+		return r.skip()
+	}
+	if tmpInfo.Elem().IsZero() {
+		parsed.decodeErr = errors.New("empty info")
+		return r.skip()
+	}
+	parsed.resp.Data.Info = tmpInfo.Elem().Interface()
+	return r.skip()
+}
+
+// nextStart returns the next start element skipping prolog tokens
+func (p *respParser) nextStart() (xml.StartElement, error) {
+	for {
+		token, err := p.d.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		if se, ok := token.(xml.StartElement); ok {
+			return se, nil
+		}
+	}
+}
+
+// elemTokenReader is a xml.TokenReader that returns buffered tokens
+// followed by tokens of the decoder until the first buffered element is closed
+type elemTokenReader struct {
+	d       *xml.Decoder
+	buf     []xml.Token
+	depth   int
+	started bool
+}
+
+// Token implements xml.TokenReader interface for r
+func (r *elemTokenReader) Token() (xml.Token, error) {
+	if r.started && r.depth == 0 {
+		return nil, io.EOF
+	}
+
+	var token xml.Token
+	if len(r.buf) != 0 {
+		token, r.buf = r.buf[0], r.buf[1:]
+	} else {
+		var err error
+		if token, err = r.d.Token(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch token.(type) {
+	case xml.StartElement:
+		r.depth++
+	case xml.EndElement:
+		r.depth--
+	}
+	r.started = true
+	return token, nil
+}
+
+// skip consumes remaining tokens of the element
+func (r *elemTokenReader) skip() error {
+	for {
+		if _, err := r.Token(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package p24
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseResp(t *testing.T) {
+	type info struct {
+		A int `xml:"a"`
+	}
+
+	t.Run("RespErr", func(t *testing.T) {
+		cases := []struct {
+			errMsg string
+			body   []byte
+		}{
+			{`For input string: "err msg"`, []byte(`<error>For input string: "err msg"</error>`)},
+			{"err msg", []byte(`<response><data><error message="err msg"></error></data></response>`)},
+			{"err msg", []byte(`<response><data><error message="err msg"/></data></response>`)},
+			{"err msg", []byte(`<response><data><oper>cmt</oper><info>err msg</info></data></response>`)},
+			{"", []byte(`<response><data><oper>cmt</oper><info><a>1</a></info></data></response>`)},
+			{"", []byte(`<response><data><oper>cmt</oper><info>  </info></data></response>`)},
+			{"", []byte(`<response><data><error message=""/><info><a>1</a></info></data></response>`)},
+		}
+
+		for i, c := range cases {
+			c := c
+			t.Run(strconv.Itoa(i), func(t *testing.T) {
+				parsed, err := parseResp(c.body, Response{Data: ResponseData{Info: info{}}}, xmlLimits{})
+				require.NoError(t, err)
+				if parsed.respErr != nil {
+					require.EqualError(t, parsed.respErr, c.errMsg)
+				} else {
+					require.Empty(t, c.errMsg)
+				}
+			})
+		}
+	})
+
+	t.Run("Content", func(t *testing.T) {
+		cases := []struct {
+			body   []byte
+			errMsg string
+		}{
+			{[]byte(`<invalid_resp>invalid data</invalid_resp>`), "unexpected root element <invalid_resp>"},
+			{[]byte(`<response><noterror><oper>cmt</oper><info></info></noterror></response>`), "invalid '<data>' tag: not found"},
+			{[]byte(`<response><data1><oper>cmt</oper><info>123</info></data1></response>`), "invalid '<data>' tag: not found"},
+			{[]byte(`<response><data><info><a>1</a></info></data><data></data></response>`), "invalid '<data>' tag: duplicated"},
+			{[]byte(`<response><data><info><a>1</a></info></response>`), "element <data> closed by </response>"},
+			{[]byte(`<response><data><info><a>1</a></info>`), "unexpected EOF"},
+			{[]byte(`<error></error>`), "empty error"},
+			{[]byte(``), "EOF"},
+			{[]byte(`<response><data><oper>cmt</oper><info>123</info></data></response>`), ""},
+		}
+
+		for i, c := range cases {
+			c := c
+			t.Run(strconv.Itoa(i), func(t *testing.T) {
+				if _, err := parseResp(c.body, Response{Data: ResponseData{Info: info{}}}, xmlLimits{}); err != nil {
+					require.NotEmpty(t, c.errMsg)
+					require.ErrorContains(t, err, c.errMsg)
+					return
+				}
+				require.Empty(t, c.errMsg)
+			})
+		}
+	})
+
+	t.Run("Decode", func(t *testing.T) {
+		cases := []struct {
+			expected Response
+			info     interface{}
+			body     []byte
+			data     string
+			errMsg   string
+		}{
+			{
+				expected: Response{
+					XMLName:      xml.Name{Local: "response"},
+					Version:      "1.0",
+					MerchantSign: MerchantSign{"id", "sign"},
+					Data:         ResponseData{Info: info{1}, Oper: "cmt"},
+				},
+				info: info{},
+				body: []byte(xml.Header + `<response version="1.0"><merchant><id>id</id><signature>sign</signature></merchant><data><oper>cmt</oper><info><a>1</a></info></data></response>`),
+				data: `<oper>cmt</oper><info><a>1</a></info>`,
+			},
+			{
+				expected: Response{
+					XMLName:      xml.Name{Local: "response"},
+					MerchantSign: MerchantSign{"id", "sign"},
+					Data:         ResponseData{Info: info{2}, Oper: "cmt"},
+				},
+				info: info{},
+				body: []byte("<response>\n  <data>\n    <info>\n      <a>2</a>\n    </info>\n    <oper>cmt</oper>\n  </data>\n  <merchant><id>id</id><signature>sign</signature></merchant>\n</response>"),
+				data: "\n    <info>\n      <a>2</a>\n    </info>\n    <oper>cmt</oper>\n  ",
+			},
+			{
+				info:   info{},
+				body:   []byte(`<response><data><oper>cmt</oper><info><b>1</b></info></data></response>`),
+				data:   `<oper>cmt</oper><info><b>1</b></info>`,
+				errMsg: "empty info",
+			},
+			{
+				info:   info{},
+				body:   []byte(`<response><data><oper>cmt</oper></data></response>`),
+				data:   `<oper>cmt</oper>`,
+				errMsg: "empty info",
+			},
+			{
+				info:   nil,
+				body:   []byte(`<response><data><oper>cmt</oper><info><a>1</a></info></data></response>`),
+				data:   `<oper>cmt</oper><info><a>1</a></info>`,
+				errMsg: "empty info",
+			},
+			{
+				info:   info{},
+				body:   []byte(`<response><data><info><a>not int</a></info><oper>cmt</oper></data><merchant><id>id</id></merchant></response>`),
+				data:   `<info><a>not int</a></info><oper>cmt</oper>`,
+				errMsg: "invalid syntax",
+			},
+		}
+
+		for i, c := range cases {
+			c := c
+			t.Run(strconv.Itoa(i), func(t *testing.T) {
+				parsed, err := parseResp(c.body, Response{Data: ResponseData{Info: c.info}}, xmlLimits{})
+				require.NoError(t, err)
+				require.Nil(t, parsed.respErr)
+				require.Equal(t, c.data, string(parsed.data))
+				if parsed.decodeErr != nil {
+					require.NotEmpty(t, c.errMsg)
+					require.ErrorContains(t, parsed.decodeErr, c.errMsg)
+					return
+				}
+				require.Empty(t, c.errMsg)
+				require.Equal(t, c.expected, parsed.resp)
+			})
+		}
+	})
+
+	t.Run("VerifySign", func(t *testing.T) {
+		signer := Merchant{"id", "pass"}
+		cases := []struct {
+			signer Merchant
+			data   []byte
+			info   info
+			errMsg string
+		}{
+			{signer, []byte("<info><a>1</a></info><oper></oper>"), info{1}, ""},
+			{signer, []byte("<info><a>2</a></info><oper></oper>"), info{2}, ""},
+			{signer, []byte("other expectedMsg"), info{1}, "invalid signature"},
+			{Merchant{"id", "other pass"}, []byte("<info><a>1</a></info><oper></oper>"), info{1}, "invalid signature"},
+			{Merchant{"other id", " ass"}, []byte("<info><a>1</a></info><oper></oper>"), info{1}, "invalid signature"},
+		}
+
+		for i, c := range cases {
+			c := c
+			t.Run(strconv.Itoa(i), func(t *testing.T) {
+				data, err := xml.Marshal(Response{
+					Data:         ResponseData{Info: c.info},
+					MerchantSign: signer.Sign(c.data),
+				})
+				require.NoError(t, err)
+
+				parsed, err := parseResp(data, Response{Data: ResponseData{Info: info{}}}, xmlLimits{})
+				require.NoError(t, err)
+				if err := c.signer.VerifySign(parsed.data, parsed.resp.MerchantSign); err != nil {
+					require.NotEmpty(t, c.errMsg)
+					require.ErrorContains(t, err, c.errMsg)
+					return
+				}
+				require.Empty(t, c.errMsg)
+			})
+		}
+	})
+}
+
+// legacyParseResp is a multi pass parsing of p24 response that was used before parseResp.
+// It is kept to compare performance of both approaches
+func legacyParseResp(body []byte, m Merchant, resp *Response) error {
+	if err := legacyRespError(body); err != nil {
+		return err
+	}
+	commonResp := func() (resp struct {
+		Data interface{} `xml:"data"`
+		Response
+	}, err error,
+	) {
+		err = xml.Unmarshal(body, &resp)
+		return
+	}
+	if _, err := commonResp(); err != nil {
+		return err
+	}
+	if _, err := legacyDataTagContent(body); err != nil {
+		return err
+	}
+	dataTag, err := legacyDataTagContent(body)
+	if err != nil {
+		return err
+	}
+	common, err := commonResp()
+	if err != nil {
+		return err
+	}
+	if err := m.VerifySign(dataTag, common.MerchantSign); err != nil {
+		return err
+	}
+	return xml.Unmarshal(body, resp)
+}
+
+func benchStatementsResp(b *testing.B, m Merchant, rows int) []byte {
+	b.Helper()
+	statements := Statements{Status: "excellent", Statements: make([]Statement, rows)}
+	for i := range statements.Statements {
+		statements.Statements[i] = Statement{
+			Card:        "1234567890123456",
+			Appcode:     strconv.Itoa(100000 + i),
+			Date:        time.Date(2021, 1, 1, 0, 0, 0, 0, kievLocation).Add(time.Duration(i) * time.Minute),
+			Terminal:    "PrivatBank, " + strconv.Itoa(i),
+			Description: strings.Repeat("description ", 4),
+			Amount:      Funds{"UAH", Amount(i)},
+			CardAmount:  Funds{"UAH", Amount(-i)},
+			Rest:        Funds{"UAH", Amount(1000000 - i)},
+		}
+	}
+	type info struct {
+		Statements Statements `xml:"statements"`
+	}
+	data, err := xml.Marshal(ResponseData{Info: info{statements}, Oper: "cmt"})
+	require.NoError(b, err)
+	dataTag, _, err := signedData([]byte("<response>" + string(data) + "</response>"))
+	require.NoError(b, err)
+
+	sign := m.Sign(dataTag)
+	return []byte(fmt.Sprintf(`%s<response version="1.0"><merchant><id>%s</id><signature>%s</signature></merchant>%s</response>`,
+		xml.Header, sign.ID, sign.Sign, data))
+}
+
+// legacyDataTagContent is '<data>' tag content extraction used before signedData
+func legacyDataTagContent(data []byte) ([]byte, error) {
+	start, end := bytes.Index(data, []byte("<data>")), bytes.LastIndex(data, []byte("</data>"))
+	if start == -1 || end == -1 {
+		return nil, errors.New("not found")
+	}
+	start += len("<data>")
+	cnt := make([]byte, end-start)
+	copy(cnt, data[start:end])
+	return cnt, nil
+}
+
+func Benchmark_parseResp(b *testing.B) {
+	type info struct {
+		Statements Statements `xml:"statements"`
+	}
+	m := Merchant{"id", "pass"}
+
+	for _, rows := range []int{10, 1000, 5000} {
+		body := benchStatementsResp(b, m, rows)
+
+		b.Run(fmt.Sprintf("SinglePass/%d", rows), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				parsed, err := parseResp(body, Response{Data: ResponseData{Info: info{}}}, xmlLimits{})
+				if err != nil || parsed.respErr != nil || parsed.decodeErr != nil {
+					b.Fatal(err, parsed.respErr, parsed.decodeErr)
+				}
+				if err := m.VerifySign(parsed.data, parsed.resp.MerchantSign); err != nil {
+					b.Fatal(err)
+				}
+				if len(parsed.resp.Data.Info.(info).Statements.Statements) != rows {
+					b.Fatal("unexpected statements count")
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("MultiPass/%d", rows), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				resp := Response{Data: ResponseData{Info: info{}}}
+				if err := legacyParseResp(body, m, &resp); err != nil {
+					b.Fatal(errors.Wrap(err, "legacy parse"))
+				}
+				if len(resp.Data.Info.(info).Statements.Statements) != rows {
+					b.Fatal("unexpected statements count")
+				}
+			}
+		})
+	}
+}
+
+// legacyRespError unmarshal body to each shape of p24 error like legacyParseResp did
+func legacyRespError(body []byte) error {
+	errResp := struct {
+		XMLName xml.Name `xml:"error"`
+		Message string   `xml:",chardata"`
+	}{}
+	if err := xml.Unmarshal(body, &errResp); err == nil && errResp.Message != "" {
+		return &respErr{errResp.Message}
+	}
+
+	dataErrResp := struct {
+		Response
+		Data struct {
+			Err struct {
+				Message string `xml:"message,attr"`
+			} `xml:"error"`
+		} `xml:"data"`
+	}{}
+	if err := xml.Unmarshal(body, &dataErrResp); err == nil && dataErrResp.Data.Err.Message != "" {
+		return &respDataErr{dataErrResp.Data.Err.Message}
+	}
+
+	infoErrResp := Response{Data: ResponseData{Info: ""}}
+	if err := xml.Unmarshal(body, &infoErrResp); err == nil {
+		return &respDataInfoErr{infoErrResp.Data.Info.(string)}
+	}
+	return nil
+}
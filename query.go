@@ -0,0 +1,480 @@
+package p24
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Query is a parsed textual filter of statements. It implements flag.Value
+// and encoding.TextUnmarshaler, so it can be parsed from command line flags or config.
+//
+// Query is a boolean expression of comparisons joined by "and", "or", "not" and parentheses,
+// "and" binds tighter than "or":
+//
+//	amount < -500 UAH and description ~ "ATM"
+//	(terminal = "PrivatBank, 123" or card = 1234567890123456) and not sign = credit
+//	date >= 2021-01-01 and date < "2021-02-01 12:00"
+//
+// Fields and their operators are:
+//
+//	amount, cardamount, rest  = != < <= > >=  amount optionally followed by a currency code
+//	date                      = != < <= > >=  date in Kyiv time zone as 2006-01-02, "2006-01-02 15:04" or "2006-01-02 15:04:05"
+//	currency                  = != ~ !~       currency code, ~ and !~ match the value as regular expression
+//	card, appcode,
+//	terminal, description     = != ~ !~       string, ~ and !~ match the value as regular expression
+//	sign                      = !=            credit or debit
+//
+// Amount compared with currency matches statements of that currency only.
+// Currency codes are three letters codes like ISO 4217 ones, they are compared ignoring case like by Currency filter.
+// Date is compared as a period of its precision, so "date = 2021-01-01" matches the whole day
+// and "date > 2021-01-01" matches statements since the next day.
+// Values can be double quoted with Go escapes. Empty query matches all statements
+type Query struct {
+	text   string
+	filter Filter
+}
+
+// ParseQuery parses text of Query. It returns *QueryError if text is invalid
+func ParseQuery(text string) (Query, error) {
+	tokens, err := lexQuery(text)
+	if err != nil {
+		return Query{}, err
+	}
+	if len(tokens) == 1 {
+		return Query{text: text}, nil
+	}
+
+	p := &queryParser{text: text, tokens: tokens}
+	filter, err := p.or()
+	if err != nil {
+		return Query{}, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return Query{}, p.errorf(t, "unexpected %s", t)
+	}
+	return Query{text: text, filter: filter}, nil
+}
+
+// Filter returns Filter of q
+func (q Query) Filter() Filter { return q.filter }
+
+// Match reports whether s matches q
+func (q Query) Match(s Statement) bool { return q.filter.Match(s) }
+
+// Apply returns statements matching q in their order
+func (q Query) Apply(statements []Statement) []Statement { return q.filter.Apply(statements) }
+
+// String returns text of q
+func (q Query) String() string { return q.text }
+
+// Set implements flag.Value interface for q
+func (q *Query) Set(text string) error {
+	parsed, err := ParseQuery(text)
+	if err != nil {
+		return err
+	}
+	*q = parsed
+	return nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface for q
+func (q Query) MarshalText() ([]byte, error) {
+	return []byte(q.text), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for q
+func (q *Query) UnmarshalText(text []byte) error {
+	return q.Set(string(text))
+}
+
+// QueryError reports invalid text of Query
+type QueryError struct {
+	Query string
+	// Pos is a byte offset of the invalid token in Query
+	Pos int
+	Err error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %v", e.Pos, e.Err)
+}
+
+func (e *QueryError) Unwrap() error { return e.Err }
+
+func (e *QueryError) Cause() error { return e.Err }
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type queryToken struct {
+	kind tokenKind
+	val  string
+	pos  int
+}
+
+func (t queryToken) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	default:
+		return strconv.Quote(t.val)
+	}
+}
+
+// isQueryWordRune reports whether r is a rune of unquoted word
+func isQueryWordRune(r rune) bool {
+	return !strings.ContainsRune(" \t\n\r\"()=!<>~", r)
+}
+
+// lexQuery splits text to tokens terminated by tokenEOF
+func lexQuery(text string) ([]queryToken, error) {
+	var tokens []queryToken
+	for i := 0; i < len(text); {
+		r := rune(text[i])
+		switch {
+		case strings.ContainsRune(" \t\n\r", r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{tokenLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{tokenRParen, ")", i})
+			i++
+		case r == '"':
+			quoted, err := strconv.QuotedPrefix(text[i:])
+			if err != nil {
+				return nil, &QueryError{text, i, errors.New("unterminated string")}
+			}
+			val, err := strconv.Unquote(quoted)
+			if err != nil {
+				return nil, &QueryError{text, i, err}
+			}
+			tokens = append(tokens, queryToken{tokenString, val, i})
+			i += len(quoted)
+		case strings.ContainsRune("=!<>~", r):
+			op := text[i : i+1]
+			if i+1 < len(text) {
+				switch text[i : i+2] {
+				case "==", "!=", "<=", ">=", "!~":
+					op = text[i : i+2]
+				}
+			}
+			if op == "!" {
+				return nil, &QueryError{text, i, errors.New(`unexpected "!"`)}
+			}
+			tokens = append(tokens, queryToken{tokenOp, op, i})
+			i += len(op)
+		default:
+			end := strings.IndexFunc(text[i:], func(r rune) bool { return !isQueryWordRune(r) })
+			if end == -1 {
+				end = len(text) - i
+			}
+			tokens = append(tokens, queryToken{tokenWord, text[i : i+end], i})
+			i += end
+		}
+	}
+	return append(tokens, queryToken{tokenEOF, "", len(text)}), nil
+}
+
+type queryParser struct {
+	text   string
+	tokens []queryToken
+	i      int
+}
+
+func (p *queryParser) peek() queryToken { return p.tokens[p.i] }
+
+func (p *queryParser) next() queryToken {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// keyword reports whether the next token is word keyword and consumes it if so
+func (p *queryParser) keyword(keyword string) bool {
+	if t := p.peek(); t.kind == tokenWord && strings.EqualFold(t.val, keyword) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// queryKeyword reports whether word is a keyword of Query
+func queryKeyword(word string) bool {
+	return strings.EqualFold(word, "and") || strings.EqualFold(word, "or") || strings.EqualFold(word, "not")
+}
+
+func (p *queryParser) errorf(t queryToken, format string, args ...interface{}) error {
+	return &QueryError{p.text, t.pos, errors.Errorf(format, args...)}
+}
+
+// or parses: and ("or" and)*
+func (p *queryParser) or() (Filter, error) {
+	filters, err := p.list("or", p.and)
+	if err != nil {
+		return nil, err
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return Or(filters...), nil
+}
+
+// and parses: unary ("and" unary)*
+func (p *queryParser) and() (Filter, error) {
+	filters, err := p.list("and", p.unary)
+	if err != nil {
+		return nil, err
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return And(filters...), nil
+}
+
+// list parses: item (sep item)*
+func (p *queryParser) list(sep string, parse func() (Filter, error)) ([]Filter, error) {
+	var filters []Filter
+	for {
+		f, err := parse()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+		if !p.keyword(sep) {
+			return filters, nil
+		}
+	}
+}
+
+// unary parses: "not" unary | "(" or ")" | comparison
+func (p *queryParser) unary() (Filter, error) {
+	if p.keyword("not") {
+		f, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(f), nil
+	}
+	if p.peek().kind == tokenLParen {
+		p.next()
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokenRParen {
+			return nil, p.errorf(t, `expected ")", got %s`, t)
+		}
+		return f, nil
+	}
+	return p.comparison()
+}
+
+// queryFunds are funds fields of Query
+var queryFunds = map[string]func(s Statement) Funds{
+	"amount":     func(s Statement) Funds { return s.Amount },
+	"cardamount": func(s Statement) Funds { return s.CardAmount },
+	"rest":       func(s Statement) Funds { return s.Rest },
+}
+
+// queryStrings are string fields of Query
+var queryStrings = map[string]func(s Statement) string{
+	"card":        func(s Statement) string { return s.Card },
+	"appcode":     func(s Statement) string { return s.Appcode },
+	"terminal":    func(s Statement) string { return s.Terminal },
+	"description": func(s Statement) string { return s.Description },
+}
+
+// comparison parses: field op value
+func (p *queryParser) comparison() (Filter, error) {
+	field := p.next()
+	if field.kind != tokenWord {
+		return nil, p.errorf(field, "expected field, got %s", field)
+	}
+	op := p.next()
+	if op.kind != tokenOp {
+		return nil, p.errorf(op, "expected operator, got %s", op)
+	}
+	if op.val == "==" {
+		op.val = "="
+	}
+	value := p.next()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, p.errorf(value, "expected value, got %s", value)
+	}
+
+	name := strings.ToLower(field.val)
+	if get, ok := queryFunds[name]; ok {
+		return p.fundsComparison(get, op, value)
+	}
+	if get, ok := queryStrings[name]; ok {
+		return p.stringComparison(get, op, value)
+	}
+	switch name {
+	case "currency":
+		return p.currencyComparison(op, value)
+	case "date":
+		return p.dateComparison(op, value)
+	case "sign":
+		return p.signComparison(op, value)
+	}
+	return nil, p.errorf(field, "unknown field %s", field)
+}
+
+var (
+	// queryCurrency matches currency code of Query, it is three letters code in any case
+	queryCurrency = regexp.MustCompile(`^[A-Za-z]{3}$`)
+	// queryLetters matches a word of letters following amount of Query that must be a currency code
+	queryLetters = regexp.MustCompile(`^[A-Za-z]+$`)
+)
+
+func (p *queryParser) fundsComparison(get func(s Statement) Funds, op, value queryToken) (Filter, error) {
+	var amount Amount
+	if err := amount.UnmarshalText([]byte(value.val)); err != nil {
+		return nil, p.errorf(value, "invalid amount %s", value)
+	}
+	currency := ""
+	if t := p.peek(); t.kind == tokenWord && queryLetters.MatchString(t.val) && !queryKeyword(t.val) {
+		if !queryCurrency.MatchString(t.val) {
+			return nil, p.errorf(t, "invalid currency %s", t)
+		}
+		currency = p.next().val
+	}
+
+	var cmp func(a Amount) bool
+	switch op.val {
+	case "=", "!=":
+		cmp = func(a Amount) bool { return a == amount }
+	case "<":
+		cmp = func(a Amount) bool { return a < amount }
+	case "<=":
+		cmp = func(a Amount) bool { return a <= amount }
+	case ">":
+		cmp = func(a Amount) bool { return a > amount }
+	case ">=":
+		cmp = func(a Amount) bool { return a >= amount }
+	default:
+		return nil, p.errorf(op, "operator %s is not supported by amount", op)
+	}
+
+	f := Filter(func(s Statement) bool {
+		funds := get(s)
+		return (currency == "" || sameCurrency(funds.Currency, currency)) && cmp(funds.Amount)
+	})
+	if op.val == "!=" {
+		return Not(f), nil
+	}
+	return f, nil
+}
+
+func (p *queryParser) currencyComparison(op, value queryToken) (Filter, error) {
+	switch op.val {
+	case "=", "!=":
+		if !queryCurrency.MatchString(value.val) {
+			return nil, p.errorf(value, "invalid currency %s", value)
+		}
+		if op.val == "!=" {
+			return Not(Currency(value.val)), nil
+		}
+		return Currency(value.val), nil
+	default:
+		return p.stringComparison(func(s Statement) string { return s.Amount.Currency }, op, value)
+	}
+}
+
+func (p *queryParser) stringComparison(get func(s Statement) string, op, value queryToken) (Filter, error) {
+	switch op.val {
+	case "=":
+		return func(s Statement) bool { return get(s) == value.val }, nil
+	case "!=":
+		return func(s Statement) bool { return get(s) != value.val }, nil
+	case "~", "!~":
+		re, err := regexp.Compile(value.val)
+		if err != nil {
+			return nil, p.errorf(value, "invalid regular expression: %v", err)
+		}
+		if op.val == "!~" {
+			return func(s Statement) bool { return !re.MatchString(get(s)) }, nil
+		}
+		return func(s Statement) bool { return re.MatchString(get(s)) }, nil
+	default:
+		return nil, p.errorf(op, "operator %s is not supported by string", op)
+	}
+}
+
+// queryDateLayouts are layouts of Query date values with their precision
+var queryDateLayouts = []struct {
+	layout    string
+	precision func(t time.Time) time.Time
+}{
+	{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"2006-01-02 15:04", func(t time.Time) time.Time { return t.Add(time.Minute) }},
+	{"2006-01-02 15:04:05", func(t time.Time) time.Time { return t.Add(time.Second) }},
+}
+
+func (p *queryParser) dateComparison(op, value queryToken) (Filter, error) {
+	var start, end time.Time
+	for _, l := range queryDateLayouts {
+		t, err := time.ParseInLocation(l.layout, value.val, kievLocation)
+		if err == nil {
+			start, end = t, l.precision(t)
+			break
+		}
+	}
+	if start.IsZero() {
+		return nil, p.errorf(value, "invalid date %s", value)
+	}
+
+	switch op.val {
+	case "=":
+		return DateRange(start, end), nil
+	case "!=":
+		return Not(DateRange(start, end)), nil
+	case "<":
+		return func(s Statement) bool { return s.Date.Before(start) }, nil
+	case "<=":
+		return func(s Statement) bool { return s.Date.Before(end) }, nil
+	case ">":
+		return func(s Statement) bool { return !s.Date.Before(end) }, nil
+	case ">=":
+		return func(s Statement) bool { return !s.Date.Before(start) }, nil
+	default:
+		return nil, p.errorf(op, "operator %s is not supported by date", op)
+	}
+}
+
+func (p *queryParser) signComparison(op, value queryToken) (Filter, error) {
+	var f Filter
+	switch strings.ToLower(value.val) {
+	case "credit":
+		f = Credit()
+	case "debit", "debet":
+		f = Debit()
+	default:
+		return nil, p.errorf(value, "invalid sign %s, expected credit or debit", value)
+	}
+
+	switch op.val {
+	case "=":
+		return f, nil
+	case "!=":
+		return Not(f), nil
+	default:
+		return nil, p.errorf(op, "operator %s is not supported by sign", op)
+	}
+}
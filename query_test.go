@@ -0,0 +1,132 @@
+package p24
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	cases := []struct {
+		query    string
+		expected []Statement
+	}{
+		{"", pick(0, 1, 2, 3)},
+		{" \t", pick(0, 1, 2, 3)},
+		{`amount < -500 UAH and description ~ "ATM"`, pick(0)},
+		{`cardamount < -500 and description ~ "^ATM"`, pick(0, 3)},
+		{`amount<-500 UAH`, pick(0)},
+		{`amount = -20 USD`, pick(3)},
+		{`amount != -50 UAH`, pick(0, 2, 3)},
+		{`rest <= 0`, pick(3)},
+		{`AMOUNT >= 1000 uah OR appcode == 123`, pick(1, 2)},
+		{`sign = credit`, pick(2)},
+		{`sign != credit`, pick(0, 1, 3)},
+		{`not sign = debit`, pick(2)},
+		{`date = 2021-01-01`, pick(0, 1)},
+		{`date != 2021-01-01`, pick(2, 3)},
+		{`date > 2021-01-01`, pick(2, 3)},
+		{`date >= 2021-01-02`, pick(2, 3)},
+		{`date < 2021-01-02`, pick(0, 1)},
+		{`date <= "2021-01-01 10:00"`, pick(0)},
+		{`date = "2021-01-01 12:00:00"`, pick(1)},
+		{`date >= 2021-01-01 and date < "2021-01-01 12:00"`, pick(0)},
+		{`(terminal = "Cafe" or card = 5555666677778888) and amount > 0`, pick(2)},
+		{`terminal = "Cafe" or card = 5555666677778888 and amount > 0`, pick(1, 2)},
+		{`currency != UAH`, pick(3)},
+		{`currency = usd`, pick(3)},
+		{`currency = THB or amount < -500 thb`, nil},
+		{`currency ~ "^U"`, pick(0, 1, 2, 3)},
+		{`amount = -20 usd or amount = -20 UAH`, pick(3)},
+		{`currency = USD and not (description !~ "abroad")`, pick(3)},
+		{`description !~ "(?i)atm"`, pick(1, 2)},
+		{`terminal ~ "\\d$"`, pick(0, 3)},
+		{`not not terminal = "Bank, 2"`, pick(3)},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			q, err := ParseQuery(c.query)
+			require.NoError(t, err)
+			require.Equal(t, c.query, q.String())
+			require.Equal(t, c.expected, q.Apply(filterStatements))
+			require.Equal(t, c.expected, q.Filter().Apply(filterStatements))
+			for _, s := range filterStatements {
+				require.Equal(t, q.Filter().Match(s), q.Match(s))
+			}
+		})
+	}
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	cases := []struct {
+		query string
+		err   string
+	}{
+		{`amount <`, `invalid query at position 8: expected value, got end of query`},
+		{`foo = 1`, `invalid query at position 0: unknown field "foo"`},
+		{`= 1`, `invalid query at position 0: expected field, got "="`},
+		{`amount 1`, `invalid query at position 7: expected operator, got "1"`},
+		{`amount ~ 1`, `invalid query at position 7: operator "~" is not supported by amount`},
+		{`amount > x`, `invalid query at position 9: invalid amount "x"`},
+		{`card < 1`, `invalid query at position 5: operator "<" is not supported by string`},
+		{`description ~ "("`, "invalid query at position 14: invalid regular expression: error parsing regexp: missing closing ): `(`"},
+		{`date = 2021-13-01`, `invalid query at position 7: invalid date "2021-13-01"`},
+		{`date ~ 2021-01-01`, `invalid query at position 5: operator "~" is not supported by date`},
+		{`sign = zero`, `invalid query at position 7: invalid sign "zero", expected credit or debit`},
+		{`sign < credit`, `invalid query at position 5: operator "<" is not supported by sign`},
+		{`(amount > 1`, `invalid query at position 11: expected ")", got end of query`},
+		{`amount > 1 1`, `invalid query at position 11: unexpected "1"`},
+		{`amount > 1 euro`, `invalid query at position 11: invalid currency "euro"`},
+		{`amount > 1 eu and sign = credit`, `invalid query at position 11: invalid currency "eu"`},
+		{`currency = usdt`, `invalid query at position 11: invalid currency "usdt"`},
+		{`currency != ""`, `invalid query at position 12: invalid currency ""`},
+		{`amount > 1 and`, `invalid query at position 14: expected field, got end of query`},
+		{`not`, `invalid query at position 3: expected field, got end of query`},
+		{`card = "abc`, `invalid query at position 7: unterminated string`},
+		{`amount ! 1`, `invalid query at position 7: unexpected "!"`},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			_, err := ParseQuery(c.query)
+			require.EqualError(t, err, c.err)
+			var queryErr *QueryError
+			require.True(t, errors.As(err, &queryErr))
+			require.Equal(t, c.query, queryErr.Query)
+		})
+	}
+}
+
+func TestQuery_Flag(t *testing.T) {
+	var q Query
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&q, "query", "statements query")
+	require.NoError(t, fs.Parse([]string{"-query", `sign = credit`}))
+	require.Equal(t, pick(2), q.Apply(filterStatements))
+
+	fs.SetOutput(io.Discard)
+	require.Error(t, fs.Parse([]string{"-query", `sign = zero`}))
+	require.Equal(t, `sign = credit`, q.String())
+}
+
+func TestQuery_JSON(t *testing.T) {
+	var config struct {
+		Query Query `json:"query"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"query":"currency = USD"}`), &config))
+	require.Equal(t, pick(3), config.Query.Apply(filterStatements))
+
+	b, err := json.Marshal(config)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"query":"currency = USD"}`, string(b))
+
+	require.Error(t, json.Unmarshal([]byte(`{"query":"currency"}`), &config))
+}
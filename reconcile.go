@@ -0,0 +1,146 @@
+package p24
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const reconcileTimeLayout = "2006-01-02 15:04:05"
+
+// Reconciliation is a report of running balance consistency of statements, see Reconcile
+type Reconciliation struct {
+	// Statements are all rows in chronological order
+	Statements []Statement
+	// Credit and Debet are totals of card amounts of all rows
+	Credit, Debet Amount
+	// HeaderCredit and HeaderDebet are totals reported by p24
+	HeaderCredit, HeaderDebet Amount
+	// Gaps are consecutive rows which rests don`t match card amount of the later one
+	Gaps []RestGap
+	// Duplicates are rows with Fingerprint of a preceding row, they are not checked by rest
+	Duplicates []Statement
+	// OutOfOrder are rows that break chronological order of the original list
+	OutOfOrder []Statement
+}
+
+// RestGap reports a rest of statement that doesn`t follow from the previous statement,
+// it usually means missing transactions between them
+type RestGap struct {
+	Prev, Next Statement
+	// Expected is rest of Next expected by rest of Prev and card amount of Next,
+	// it is zero if currencies of them differ
+	Expected Funds
+	// Missing is a total amount of missing transactions, it is rest of Next minus Expected
+	Missing Amount
+}
+
+// Reconcile checks running balance of statements. It orders rows chronologically,
+// checks each rest is the previous rest plus card amount of the row and compares totals
+// of card amounts with Credit and Debet of statements.
+// List of statements in reverse chronological order is accepted as ordered.
+// Rows of the same date are ordered by their rests if possible
+func Reconcile(statements Statements) Reconciliation {
+	r := Reconciliation{HeaderCredit: statements.Credit, HeaderDebet: statements.Debet}
+	rows := make([]Statement, len(statements.Statements))
+	copy(rows, statements.Statements)
+	if len(rows) > 1 && rows[0].Date.After(rows[len(rows)-1].Date) {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	var last time.Time
+	for _, s := range rows {
+		if s.Date.Before(last) {
+			r.OutOfOrder = append(r.OutOfOrder, s)
+		} else {
+			last = s.Date
+		}
+		if amount := s.CardAmount.Amount; amount < 0 {
+			r.Debet -= amount
+		} else {
+			r.Credit += amount
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Date.Before(rows[j].Date) })
+	r.Statements = chainRests(rows)
+
+	var prev *Statement
+	seen := make(map[string]bool, len(rows))
+	for i := range r.Statements {
+		s := &r.Statements[i]
+		fp := s.Fingerprint()
+		if seen[fp] {
+			r.Duplicates = append(r.Duplicates, *s)
+			continue
+		}
+		seen[fp] = true
+		if prev != nil {
+			if expected := nextRest(*prev, *s); expected != s.Rest {
+				r.Gaps = append(r.Gaps, RestGap{Prev: *prev, Next: *s, Expected: expected, Missing: s.Rest.Amount - expected.Amount})
+			}
+		}
+		prev = s
+	}
+	return r
+}
+
+// chainRests reorders rows of the same date so that each rest follows from the previous row if possible
+func chainRests(rows []Statement) []Statement {
+	for i := 1; i < len(rows); i++ {
+		if nextRest(rows[i-1], rows[i]) == rows[i].Rest {
+			continue
+		}
+		for j := i + 1; j < len(rows) && rows[j].Date.Equal(rows[i].Date); j++ {
+			if nextRest(rows[i-1], rows[j]) == rows[j].Rest {
+				s := rows[j]
+				copy(rows[i+1:j+1], rows[i:j])
+				rows[i] = s
+				break
+			}
+		}
+	}
+	return rows
+}
+
+// nextRest returns rest of s expected by rest of prev and card amount of s
+func nextRest(prev, s Statement) Funds {
+	if prev.Rest.Currency != s.CardAmount.Currency {
+		return Funds{}
+	}
+	return Funds{prev.Rest.Currency, prev.Rest.Amount + s.CardAmount.Amount}
+}
+
+// Balanced reports whether totals of card amounts match totals reported by p24
+func (r Reconciliation) Balanced() bool {
+	return r.Credit == r.HeaderCredit && r.Debet == r.HeaderDebet
+}
+
+// Valid reports whether statements are balanced and have no gaps, duplicates and out of order rows
+func (r Reconciliation) Valid() bool {
+	return r.Balanced() && len(r.Gaps) == 0 && len(r.Duplicates) == 0 && len(r.OutOfOrder) == 0
+}
+
+// Problems returns descriptions of found problems
+func (r Reconciliation) Problems() []string {
+	var problems []string
+	if r.Credit != r.HeaderCredit {
+		problems = append(problems, fmt.Sprintf("credit mismatch: rows %s, header %s", r.Credit, r.HeaderCredit))
+	}
+	if r.Debet != r.HeaderDebet {
+		problems = append(problems, fmt.Sprintf("debet mismatch: rows %s, header %s", r.Debet, r.HeaderDebet))
+	}
+	for _, gap := range r.Gaps {
+		problems = append(problems, fmt.Sprintf("rest gap between %s and %s: expected %s, got %s, missing %s",
+			gap.Prev.Date.Format(reconcileTimeLayout), gap.Next.Date.Format(reconcileTimeLayout),
+			gap.Expected, gap.Next.Rest, gap.Missing))
+	}
+	for _, s := range r.Duplicates {
+		problems = append(problems, fmt.Sprintf("duplicate of %s %s", s.Date.Format(reconcileTimeLayout), s.CardAmount))
+	}
+	for _, s := range r.OutOfOrder {
+		problems = append(problems, fmt.Sprintf("out of order %s %s", s.Date.Format(reconcileTimeLayout), s.CardAmount))
+	}
+	return problems
+}
@@ -0,0 +1,101 @@
+package p24
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcile(t *testing.T) {
+	row := func(hour int, amount, rest Amount) Statement {
+		return Statement{
+			Card:       "1234567890123456",
+			Date:       time.Date(2021, 1, 1, hour, 0, 0, 0, kievLocation),
+			Amount:     Funds{"UAH", amount},
+			CardAmount: Funds{"UAH", amount},
+			Rest:       Funds{"UAH", rest},
+		}
+	}
+	// two identical coffees at 11:00
+	a, b, c, d := row(10, -100, 900), row(11, -50, 850), row(11, -50, 800), row(12, 500, 1300)
+	// card amount in other currency than rest
+	usd := row(11, -50, 850)
+	usd.CardAmount.Currency = "USD"
+	statements := func(credit, debet Amount, rows ...Statement) Statements {
+		return Statements{Credit: credit, Debet: debet, Statements: rows}
+	}
+
+	cases := []struct {
+		statements Statements
+		expected   Reconciliation
+		problems   []string
+	}{
+		{
+			statements: Statements{},
+			expected:   Reconciliation{Statements: []Statement{}},
+		},
+		{
+			statements: statements(500, 200, a, b, c, d),
+			expected:   Reconciliation{Statements: []Statement{a, b, c, d}, Credit: 500, Debet: 200, HeaderCredit: 500, HeaderDebet: 200},
+		},
+		{
+			statements: statements(500, 200, d, c, b, a),
+			expected:   Reconciliation{Statements: []Statement{a, b, c, d}, Credit: 500, Debet: 200, HeaderCredit: 500, HeaderDebet: 200},
+		},
+		{
+			statements: statements(500, 200, a, c, b, d),
+			expected:   Reconciliation{Statements: []Statement{a, b, c, d}, Credit: 500, Debet: 200, HeaderCredit: 500, HeaderDebet: 200},
+		},
+		{
+			statements: statements(500, 200, a, c, d),
+			expected: Reconciliation{
+				Statements: []Statement{a, c, d}, Credit: 500, Debet: 150, HeaderCredit: 500, HeaderDebet: 200,
+				Gaps: []RestGap{{Prev: a, Next: c, Expected: Funds{"UAH", 850}, Missing: -50}},
+			},
+			problems: []string{
+				"debet mismatch: rows 1.50, header 2",
+				"rest gap between 2021-01-01 10:00:00 and 2021-01-01 11:00:00: expected 8.50 UAH, got 8 UAH, missing -0.50",
+			},
+		},
+		{
+			statements: statements(500, 200, a, b, c, d, d),
+			expected: Reconciliation{
+				Statements: []Statement{a, b, c, d, d}, Credit: 1000, Debet: 200, HeaderCredit: 500, HeaderDebet: 200,
+				Duplicates: []Statement{d},
+			},
+			problems: []string{"credit mismatch: rows 10, header 5", "duplicate of 2021-01-01 12:00:00 5 UAH"},
+		},
+		{
+			statements: statements(500, 200, a, b, d, c),
+			expected: Reconciliation{
+				Statements: []Statement{a, b, c, d}, Credit: 500, Debet: 200, HeaderCredit: 500, HeaderDebet: 200,
+				OutOfOrder: []Statement{c},
+			},
+			problems: []string{"out of order 2021-01-01 11:00:00 -0.50 UAH"},
+		},
+		{
+			statements: statements(0, 100, a, usd),
+			expected: Reconciliation{
+				Statements: []Statement{a, usd},
+				Credit:     0, Debet: 150, HeaderCredit: 0, HeaderDebet: 100,
+				Gaps: []RestGap{{Prev: a, Next: usd, Missing: 850}},
+			},
+		},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			original := append([]Statement(nil), c.statements.Statements...)
+			r := Reconcile(c.statements)
+			require.Equal(t, c.expected, r)
+			require.Equal(t, c.expected.Balanced() && c.expected.Gaps == nil && c.problems == nil, r.Valid())
+			if c.problems != nil {
+				require.Equal(t, c.problems, r.Problems())
+			}
+			require.Equal(t, original, c.statements.Statements)
+		})
+	}
+}
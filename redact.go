@@ -0,0 +1,101 @@
+package p24
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Redaction configures masking of sensitive data in *Error and Client log events.
+// Zero value masks card numbers, signatures, merchant ids and amounts
+type Redaction struct {
+	// KeepRawBodies keeps Req and Resp bodies of *Error as is.
+	// Error messages and log events are redacted anyway.
+	// It is intended for local debugging only
+	KeepRawBodies bool
+
+	// KeepMerchantID disables masking of merchant ids
+	KeepMerchantID bool
+
+	// KeepAmounts disables masking of amounts and balances
+	KeepAmounts bool
+}
+
+const redacted = "[REDACTED]"
+
+var (
+	redactCardNumber  = regexp.MustCompile(`\b(\d{6})\d{6}(\d{4})\b`)
+	redactSignature   = regexp.MustCompile(`\b[0-9a-fA-F]{40}\b`)
+	redactSignTag     = regexp.MustCompile(`(<signature>)[^<]*(</signature>)`)
+	redactMerchantID  = regexp.MustCompile(`(<merchant>\s*<id>)[^<]*(</id>)`)
+	redactAmountAttr  = regexp.MustCompile(`\b((?:amount|cardamount|rest|credit|debet)=")[^"]*(")`)
+	redactAmountTag   = regexp.MustCompile(`(<(av_balance|balance|fin_limit|trade_limit)>)[^<]*(</)`)
+	redactAmountFunds = regexp.MustCompile(`-?\b\d+(?:\.\d+)? [A-Z]{3}\b`)
+)
+
+// Redact returns s with card numbers, signatures, merchant ids and amounts masked according to r.
+// Card numbers keep the first six and the last four digits
+func (r Redaction) Redact(s string) string {
+	s = redactCardNumber.ReplaceAllString(s, "$1******$2")
+	s = redactSignTag.ReplaceAllString(s, "${1}"+redacted+"${2}")
+	s = redactSignature.ReplaceAllString(s, redacted)
+	if !r.KeepMerchantID {
+		s = redactMerchantID.ReplaceAllString(s, "${1}"+redacted+"${2}")
+	}
+	if !r.KeepAmounts {
+		s = redactAmountAttr.ReplaceAllString(s, "${1}"+redacted+"${2}")
+		s = redactAmountTag.ReplaceAllString(s, "${1}"+redacted+"${3}")
+		s = redactAmountFunds.ReplaceAllString(s, redacted)
+	}
+	return s
+}
+
+// merchantID returns masked merchant id or id itself if r keeps merchant ids or id is empty
+func (r Redaction) merchantID(id string) string {
+	if r.KeepMerchantID || id == "" {
+		return id
+	}
+	return redacted
+}
+
+// body returns redacted copy of http body or body itself if r keeps raw bodies
+func (r Redaction) body(body []byte) []byte {
+	if r.KeepRawBodies || body == nil {
+		return body
+	}
+	return []byte(r.Redact(string(body)))
+}
+
+// value returns redacted string representation of v if it is a string, error or fmt.Stringer
+func (r Redaction) value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.Redact(v)
+	case error:
+		return r.Redact(v.Error())
+	case fmt.Stringer:
+		return r.Redact(v.String())
+	default:
+		return v
+	}
+}
+
+// redactError returns err with redacted message or err itself if there is nothing to redact
+func (r Redaction) redactError(err error) error {
+	msg := err.Error()
+	if s := r.Redact(msg); s != msg {
+		return &redactedError{err: err, msg: s}
+	}
+	return err
+}
+
+// redactedError is an error with redacted message of the original error
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+func (e *redactedError) Cause() error { return e.err }
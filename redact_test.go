@@ -0,0 +1,141 @@
+package p24
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedaction_Redact(t *testing.T) {
+	body := `<request version="1.0"><merchant><id>75482</id><signature>ad67cf1c11e0f87bedac2c9bb260e3abf54e9862</signature></merchant>` +
+		`<data><payment id=""><prop name="card" value="1234567890123456"></prop></payment></data></request>`
+	statement := `<statement card="1234567890123456" amount="-10.00 UAH" cardamount="-10.00 UAH" rest="990.50 UAH" description="ATM"/>`
+	balance := `<cardbalance><av_balance>1000.00</av_balance><balance>1000.00</balance><fin_limit>0.0</fin_limit></cardbalance>`
+
+	cases := []struct {
+		redaction Redaction
+		s         string
+		expected  string
+	}{
+		{s: "card 1234567890123456", expected: "card 123456******3456"},
+		{s: "sign ad67cf1c11e0f87bedac2c9bb260e3abf54e9862", expected: "sign [REDACTED]"},
+		{s: "12345678901234567890", expected: "12345678901234567890"},
+		{s: "<signature>short</signature>", expected: "<signature>[REDACTED]</signature>"},
+		{
+			s: body,
+			expected: `<request version="1.0"><merchant><id>[REDACTED]</id><signature>[REDACTED]</signature></merchant>` +
+				`<data><payment id=""><prop name="card" value="123456******3456"></prop></payment></data></request>`,
+		},
+		{
+			redaction: Redaction{KeepMerchantID: true},
+			s:         `<merchant><id>75482</id></merchant>`,
+			expected:  `<merchant><id>75482</id></merchant>`,
+		},
+		{
+			s:        statement,
+			expected: `<statement card="123456******3456" amount="[REDACTED]" cardamount="[REDACTED]" rest="[REDACTED]" description="ATM"/>`,
+		},
+		{
+			redaction: Redaction{KeepAmounts: true},
+			s:         statement,
+			expected:  `<statement card="123456******3456" amount="-10.00 UAH" cardamount="-10.00 UAH" rest="990.50 UAH" description="ATM"/>`,
+		},
+		{
+			s:        balance,
+			expected: `<cardbalance><av_balance>[REDACTED]</av_balance><balance>[REDACTED]</balance><fin_limit>[REDACTED]</fin_limit></cardbalance>`,
+		},
+		{s: "not enough funds: -10.50 UAH", expected: "not enough funds: [REDACTED]"},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require.Equal(t, c.expected, c.redaction.Redact(c.s))
+		})
+	}
+}
+
+func TestRedaction_value(t *testing.T) {
+	cases := []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{"card 1234567890123456", "card 123456******3456"},
+		{errors.New("invalid card 1234567890123456"), "invalid card 123456******3456"},
+		{"sign ad67cf1c11e0f87bedac2c9bb260e3abf54e9862", "sign [REDACTED]"},
+		{12, 12},
+		{EndpointStatements, EndpointStatements},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require.Equal(t, c.expected, Redaction{}.value(c.value))
+		})
+	}
+}
+
+func TestClient_DoContext_Redaction(t *testing.T) {
+	respBody := `<response><data><error message="invalid card 1234567890123456"/></data>` +
+		`<merchant><id>75482</id><signature>ad67cf1c11e0f87bedac2c9bb260e3abf54e9862</signature></merchant></response>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(respBody))
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		redaction Redaction
+		rawBodies bool
+	}{
+		{},
+		{redaction: Redaction{KeepRawBodies: true}, rawBodies: true},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			var logs []string
+			cli, err := NewClient(ClientOpts{
+				HTTP:      http.DefaultClient,
+				BaseURL:   srv.URL + "/",
+				Merchant:  Merchant{"75482", "pass"},
+				Redaction: c.redaction,
+				StructuredLog: LogfAdapter{Logger: LogFunc(func(format string, v ...interface{}) {
+					logs = append(logs, format)
+					for _, a := range v {
+						logs = append(logs, a.(string))
+					}
+				}), MinLevel: LevelDebug},
+			})
+			require.NoError(t, err)
+
+			_, err = cli.GetCardBalance(context.Background(), BalanceOpts{CardNumber: "1234567890123456"})
+			var p24Err *Error
+			require.True(t, errors.As(err, &p24Err))
+			require.ErrorIs(t, err, ErrBadCardNumber)
+			require.EqualError(t, err, "xml response with error: invalid card 123456******3456")
+
+			if c.rawBodies {
+				require.Equal(t, respBody, string(p24Err.Resp))
+				require.Contains(t, string(p24Err.Req), `value="1234567890123456"`)
+			} else {
+				for _, body := range []string{string(p24Err.Req), string(p24Err.Resp)} {
+					require.NotContains(t, body, "1234567890123456")
+					require.NotContains(t, body, "75482")
+					require.Contains(t, body, "<signature>[REDACTED]</signature>")
+				}
+			}
+
+			out := strings.Join(logs, "")
+			require.Contains(t, out, "p24 response rejected")
+			require.NotContains(t, out, "1234567890123456")
+			require.NotContains(t, out, "ad67cf1c11e0f87bedac2c9bb260e3abf54e9862")
+		})
+	}
+}
@@ -2,6 +2,8 @@ package p24
 
 import (
 	"encoding/xml"
+
+	"github.com/pkg/errors"
 )
 
 const (
@@ -44,15 +46,26 @@ type Request struct {
 
 // NewRequest returns Request with MerchantSign of reqData
 func NewRequest(m Merchant, reqData RequestData) Request {
+	req := newRequest(reqData)
+	data, _ := requestData(req)
+	req.MerchantSign = m.Sign(data)
+	return req
+}
+
+// newRequest returns unsigned Request of reqData with default CommonOpts if they are empty
+func newRequest(reqData RequestData) Request {
 	if zero := (CommonOpts{}); reqData.CommonOpts == zero {
 		reqData.CommonOpts = DefaultCommonOpts()
 	}
+	return Request{Version: "1.0", Data: reqData}
+}
 
-	xmlData, _ := xml.Marshal(reqData)
-	dataTag, _ := dataTagContent(xmlData)
-	return Request{
-		Version:      "1.0",
-		MerchantSign: m.Sign(dataTag),
-		Data:         reqData,
+// requestData returns '<data>' content of req to be signed
+func requestData(req Request) ([]byte, error) {
+	xmlReq, err := xml.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "can`t marshal request")
 	}
+	data, _, err := signedData(xmlReq)
+	return data, err
 }
@@ -84,25 +84,9 @@ func (re *respDataErr) Error() string {
 	return re.msg
 }
 
-// UnmarshalXML implement xml.Unmarshaler interface for re
-func (re *respDataErr) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
-	resp := &struct {
-		Response
-		Data struct {
-			Err struct {
-				Message string `xml:"message,attr"`
-			} `xml:"error"`
-		} `xml:"data"`
-	}{}
-
-	if err := d.DecodeElement(resp, &start); err != nil {
-		return err
-	}
-	if resp.Data.Err.Message == "" {
-		return errors.New("empty error")
-	}
-	re.msg = resp.Data.Err.Message
-	return nil
+// Is reports whether target is ErrP24 or sentinel of re message
+func (re *respDataErr) Is(target error) bool {
+	return isP24Error(re.msg, target)
 }
 
 // respDataInfoErr struct for mapping p24 response errors
@@ -116,19 +100,9 @@ func (re *respDataInfoErr) Error() string {
 	return re.msg
 }
 
-// UnmarshalXML implement xml.Unmarshaler interface for re
-// nolint:gocyclo // UnmarshalXML is a complexity operation
-func (re *respDataInfoErr) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
-	resp := &Response{
-		Data: ResponseData{
-			Info: "",
-		},
-	}
-	if err := d.DecodeElement(resp, &start); err != nil {
-		return err
-	}
-	re.msg = resp.Data.Info.(string)
-	return nil
+// Is reports whether target is ErrP24 or sentinel of re message
+func (re *respDataInfoErr) Is(target error) bool {
+	return isP24Error(re.msg, target)
 }
 
 // respErr struct for mapping p24 response errors
@@ -142,19 +116,7 @@ func (re *respErr) Error() string {
 	return re.msg
 }
 
-// UnmarshalXML implement xml.Unmarshaler interface for re
-// nolint:gocyclo // UnmarshalXML is a complexity operation
-func (re *respErr) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
-	resp := &struct {
-		XMLName xml.Name `xml:"error"`
-		Message string   `xml:",chardata"`
-	}{}
-	if err := d.DecodeElement(resp, &start); err != nil {
-		return err
-	}
-	if resp.Message == "" {
-		return errors.New("empty error")
-	}
-	re.msg = resp.Message
-	return nil
+// Is reports whether target is ErrP24 or sentinel of re message
+func (re *respErr) Is(target error) bool {
+	return isP24Error(re.msg, target)
 }
@@ -100,12 +100,8 @@ func Test_respDataErr(t *testing.T) {
 
 	for i, c := range cases {
 		c := c
-		t.Run(fmt.Sprintf("UnmarshalXML/%d", i), func(t *testing.T) {
-			actual := &respDataErr{}
-			if err := xml.Unmarshal(c.resp, actual); err != nil {
-				require.Empty(t, c.expectedMsg)
-			}
-			require.EqualError(t, actual, c.expectedMsg)
+		t.Run(fmt.Sprintf("parseResp/%d", i), func(t *testing.T) {
+			requireRespErr(t, c.resp, &respDataErr{c.expectedMsg}, c.expectedMsg)
 		})
 	}
 }
@@ -121,12 +117,8 @@ func Test_respDataInfoErr(t *testing.T) {
 
 	for i, c := range cases {
 		c := c
-		t.Run(fmt.Sprintf("UnmarshalXML/%d", i), func(t *testing.T) {
-			actual := &respDataInfoErr{}
-			if err := xml.Unmarshal(c.resp, actual); err != nil {
-				require.Empty(t, c.expectedMsg)
-			}
-			require.EqualError(t, actual, c.expectedMsg)
+		t.Run(fmt.Sprintf("parseResp/%d", i), func(t *testing.T) {
+			requireRespErr(t, c.resp, &respDataInfoErr{c.expectedMsg}, c.expectedMsg)
 		})
 	}
 }
@@ -137,17 +129,30 @@ func Test_respErr(t *testing.T) {
 		resp        []byte
 	}{
 		{`For input string: "error msg"`, []byte(`<error>For input string: "error msg"</error>`)},
+		{"", []byte(`<error></error>`)},
 		{"", []byte(`<error>For input string: "error msg"</error1>`)},
 	}
 
 	for i, c := range cases {
 		c := c
-		t.Run(fmt.Sprintf("UnmarshalXML/%d", i), func(t *testing.T) {
-			actual := &respErr{}
-			if err := xml.Unmarshal(c.resp, actual); err != nil {
-				require.Empty(t, c.expectedMsg)
-			}
-			require.EqualError(t, actual, c.expectedMsg)
+		t.Run(fmt.Sprintf("parseResp/%d", i), func(t *testing.T) {
+			requireRespErr(t, c.resp, &respErr{c.expectedMsg}, c.expectedMsg)
 		})
 	}
 }
+
+// requireRespErr requires parseResp of resp reports expected p24 error or no error if expectedMsg is empty
+func requireRespErr(t *testing.T, resp []byte, expected error, expectedMsg string) {
+	t.Helper()
+	parsed, err := parseResp(resp, Response{Data: ResponseData{Info: struct {
+		Test string `xml:"test"`
+	}{}}}, xmlLimits{})
+	if expectedMsg == "" {
+		if err == nil {
+			require.NoError(t, parsed.respErr)
+		}
+		return
+	}
+	require.NoError(t, err)
+	require.Equal(t, expected, parsed.respErr)
+}
@@ -0,0 +1,114 @@
+package p24
+
+import (
+	"context"
+	"math"
+	// nolint:gosec // jitter does not require crypto rand
+	"math/rand"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy defines how Client retries failed p24 api calls.
+// Zero value RetryPolicy performs exactly one attempt
+type RetryPolicy struct {
+	// Retryable reports whether err worth to be retried.
+	// IsRetryable is used if nil
+	Retryable func(err error) bool
+
+	// MaxAttempts is a max number of attempts including the first one
+	MaxAttempts int
+
+	// InitialBackoff is a delay before the second attempt
+	InitialBackoff time.Duration
+
+	// MaxBackoff is an upper bound of a delay between attempts
+	MaxBackoff time.Duration
+
+	// Multiplier is a factor the delay is multiplied by after each attempt.
+	// 2 is used if less than 1
+	Multiplier float64
+
+	// Jitter is a fraction [0, 1] of a delay to be randomly subtracted from it
+	Jitter float64
+}
+
+// DefaultRetryPolicy returns RetryPolicy with 3 attempts and exponential backoff
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// attempts returns a number of attempts to be performed by p
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// retryable reports whether err worth to be retried by p
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// Backoff returns a delay before the next attempt after the given failed attempt (starts from 1)
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if jitter := math.Min(math.Max(p.Jitter, 0), 1); jitter > 0 {
+		delay -= delay * jitter * rand.Float64() // nolint:gosec // jitter does not require crypto rand
+	}
+	return time.Duration(delay)
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// IsRetryable reports whether err is a temporary failure of p24 api call:
+// a network error including a timeout of http client, an unexpected 5xx http status code,
+// ErrTemporary or ErrRateLimited p24 error.
+// Signature, certificate pin, validation errors and context cancellation are never retryable.
+// Client doesn`t retry calls which context is done regardless of RetryPolicy Retryable
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrPinMismatch) {
+		return false
+	}
+
+	var statusErr *statusCodeError
+	switch {
+	case errors.Is(err, ErrTransport):
+		return true
+	case errors.As(err, &statusErr):
+		return statusErr.code >= 500
+	case errors.Is(err, ErrP24):
+		return errors.Is(err, ErrTemporary) || errors.Is(err, ErrRateLimited)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,155 @@
+package p24
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 3}
+	require.Equal(t, 100*time.Millisecond, p.Backoff(1))
+	require.Equal(t, 300*time.Millisecond, p.Backoff(2))
+	require.Equal(t, 900*time.Millisecond, p.Backoff(3))
+	require.Equal(t, time.Second, p.Backoff(4))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.Backoff(1)
+		require.True(t, 50*time.Millisecond <= d && d <= 100*time.Millisecond, d)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		err       error
+		retryable bool
+	}{
+		{nil, false},
+		{context.Canceled, false},
+		{newError(&transportError{errors.Wrap(context.DeadlineExceeded, "http request failed")}, "", "", nil, nil), true},
+		{newError(&transportError{errors.New("connection reset")}, "", "", nil, nil), true},
+		{newError(&statusCodeError{502}, "", "", nil, nil), true},
+		{newError(&statusCodeError{404}, "", "", nil, nil), false},
+		{newError(errors.Wrap(&respDataErr{"Service temporarily unavailable"}, "xml response with error"), "", "", nil, nil), true},
+		{newError(errors.Wrap(&respErr{"Request timeout"}, "xml response with error"), "", "", nil, nil), true},
+		{newError(errors.Wrap(&respDataErr{"invalid signature"}, "xml response with error"), "", "", nil, nil), false},
+		{newError(errors.Wrap(&respDataInfoErr{"this ip is not allowed"}, "xml response with error"), "", "", nil, nil), false},
+		{newError(errors.New("xml response with invalid signature"), "", "", nil, nil), false},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require.Equal(t, c.retryable, IsRetryable(c.err))
+		})
+	}
+}
+
+func TestClient_DoContext_Retry(t *testing.T) {
+	okBody := []byte(xml.Header + `<response><data><info><test>test</test></info><oper>cmt</oper></data><merchant><id>id</id><signature>ad67cf1c11e0f87bedac2c9bb260e3abf54e9862</signature></merchant></response>`)
+	type info struct {
+		Test string `xml:"test"`
+	}
+
+	cases := []struct {
+		codes    []int
+		policy   RetryPolicy
+		attempts int
+		errMsg   string
+	}{
+		{[]int{500, 503, 200}, RetryPolicy{MaxAttempts: 3}, 3, ""},
+		{[]int{500, 500, 500}, RetryPolicy{MaxAttempts: 2}, 2, "unexpected http status code 500"},
+		{[]int{400, 200}, RetryPolicy{MaxAttempts: 3}, 1, "unexpected http status code 400"},
+		{[]int{500, 200}, RetryPolicy{}, 1, "unexpected http status code 500"},
+		{[]int{500, 200}, RetryPolicy{MaxAttempts: 3, Retryable: func(error) bool { return false }}, 1, "unexpected http status code 500"},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			attempts := 0
+			var do DoFunc = func(req *http.Request) (*http.Response, error) {
+				code := c.codes[attempts]
+				attempts++
+				tr := httptest.NewRecorder()
+				_, _ = tr.Write(okBody)
+				tr.Code = code
+				return tr.Result(), nil
+			}
+
+			cli := Client{http: do, merchant: Merchant{"id", "pass"}, retry: c.policy, log: LogfAdapter{Logger: LogFunc(t.Logf), MinLevel: LevelDebug}}
+			resp := Response{Data: ResponseData{Info: info{}}}
+			err := cli.DoContext(context.Background(), "http://localhost", http.MethodPost, Request{}, &resp)
+			require.Equal(t, c.attempts, attempts)
+			if err != nil {
+				require.NotEmpty(t, c.errMsg)
+				require.ErrorContains(t, err, c.errMsg)
+				return
+			}
+			require.Empty(t, c.errMsg)
+			require.Equal(t, info{"test"}, resp.Data.Info)
+		})
+	}
+
+	t.Run("HTTPClientTimeout", func(t *testing.T) {
+		var attempts int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&attempts, 1) < 3 {
+				time.Sleep(200 * time.Millisecond)
+			}
+			_, _ = w.Write(okBody)
+		}))
+		defer srv.Close()
+
+		cli := Client{
+			http:     &http.Client{Timeout: 50 * time.Millisecond},
+			merchant: Merchant{"id", "pass"},
+			retry:    RetryPolicy{MaxAttempts: 3},
+			log:      LogfAdapter{Logger: LogFunc(t.Logf), MinLevel: LevelDebug},
+		}
+		resp := Response{Data: ResponseData{Info: info{}}}
+		require.NoError(t, cli.DoContext(context.Background(), srv.URL, http.MethodPost, Request{}, &resp))
+		require.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+		require.Equal(t, info{"test"}, resp.Data.Info)
+	})
+
+	t.Run("ContextDeadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		attempts := 0
+		var do DoFunc = func(req *http.Request) (*http.Response, error) {
+			attempts++
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+
+		cli := Client{http: do, retry: RetryPolicy{MaxAttempts: 5}, log: LogfAdapter{Logger: LogFunc(t.Logf), MinLevel: LevelDebug}}
+		err := cli.DoContext(ctx, "http://localhost", http.MethodPost, Request{}, &Response{})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("ContextCanceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		attempts := 0
+		var do DoFunc = func(req *http.Request) (*http.Response, error) {
+			attempts++
+			cancel()
+			return nil, errors.New("connection refused")
+		}
+
+		cli := Client{http: do, retry: RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour}, log: LogfAdapter{Logger: LogFunc(t.Logf), MinLevel: LevelDebug}}
+		err := cli.DoContext(ctx, "http://localhost", http.MethodPost, Request{}, &Response{})
+		require.ErrorContains(t, err, "connection refused")
+		require.Equal(t, 1, attempts)
+	})
+}
@@ -0,0 +1,245 @@
+package p24
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Signer signs p24 request data and data of p24 responses to verify their signatures.
+// It allows to keep merchant password out of Client, e.g. in a secrets manager or an isolated process.
+// Merchant is a Signer that signs with its own password
+type Signer interface {
+	// SignData returns MerchantSign of data
+	SignData(ctx context.Context, data []byte) (MerchantSign, error)
+}
+
+// CredentialsProvider provides current merchant credentials.
+// Merchant is a CredentialsProvider that returns itself
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (Merchant, error)
+}
+
+// SignData implements Signer interface for m
+func (m Merchant) SignData(_ context.Context, data []byte) (MerchantSign, error) {
+	return m.Sign(data), nil
+}
+
+// Credentials implements CredentialsProvider interface for m
+func (m Merchant) Credentials(context.Context) (Merchant, error) {
+	return m, nil
+}
+
+// snapshotSigner is a Signer which credentials can change between calls
+type snapshotSigner interface {
+	// snapshot returns Signer with current credentials
+	snapshot(ctx context.Context) (Signer, error)
+}
+
+// NewCredentialsSigner returns Signer that signs data with current credentials of p.
+// Every call of SignData gets credentials from p, so rotated credentials are used immediately
+func NewCredentialsSigner(p CredentialsProvider) Signer {
+	return credentialsSigner{p}
+}
+
+type credentialsSigner struct {
+	p CredentialsProvider
+}
+
+// Format implements fmt.Formatter for s, credentials are formatted with masked password
+func (s credentialsSigner) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, "p24.credentialsSigner{"+formatVerb(f, verb)+"}", s.p)
+}
+
+// snapshot implements snapshotSigner interface for s.
+// Client signs request and verifies its response with the same snapshot,
+// so credentials rotated in the middle of a call don`t break its signature verification
+func (s credentialsSigner) snapshot(ctx context.Context) (Signer, error) {
+	m, err := s.p.Credentials(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "can`t get merchant credentials")
+	}
+	return m, nil
+}
+
+func (s credentialsSigner) SignData(ctx context.Context, data []byte) (MerchantSign, error) {
+	m, err := s.p.Credentials(ctx)
+	if err != nil {
+		return MerchantSign{}, errors.Wrap(err, "can`t get merchant credentials")
+	}
+	return m.Sign(data), nil
+}
+
+// Default environment variables of EnvCredentials
+const (
+	DefaultEnvMerchantID   = "P24_MERCHANT_ID"
+	DefaultEnvMerchantPass = "P24_MERCHANT_PASS"
+)
+
+// EnvCredentials is a CredentialsProvider that reads merchant credentials from environment variables
+type EnvCredentials struct {
+	// IDKey is a name of variable with merchant id. DefaultEnvMerchantID is used if empty
+	IDKey string
+	// PassKey is a name of variable with merchant password. DefaultEnvMerchantPass is used if empty
+	PassKey string
+}
+
+// Credentials implements CredentialsProvider interface for e
+func (e EnvCredentials) Credentials(context.Context) (Merchant, error) {
+	idKey, passKey := e.IDKey, e.PassKey
+	if idKey == "" {
+		idKey = DefaultEnvMerchantID
+	}
+	if passKey == "" {
+		passKey = DefaultEnvMerchantPass
+	}
+
+	m := Merchant{ID: os.Getenv(idKey), Pass: os.Getenv(passKey)}
+	if m.ID == "" || m.Pass == "" {
+		return Merchant{}, errors.Errorf("empty %s or %s environment variable", idKey, passKey)
+	}
+	return m, nil
+}
+
+// FileCredentials is a CredentialsProvider that reads merchant credentials from json file
+// like {"id": "merchant id", "pass": "merchant password"}
+type FileCredentials struct {
+	Path string
+}
+
+// Credentials implements CredentialsProvider interface for f
+func (f FileCredentials) Credentials(context.Context) (Merchant, error) {
+	b, err := os.ReadFile(f.Path)
+	if err != nil {
+		return Merchant{}, errors.Wrap(err, "can`t read credentials file")
+	}
+
+	var creds struct {
+		ID   string `json:"id"`
+		Pass string `json:"pass"`
+	}
+	if err := json.Unmarshal(b, &creds); err != nil {
+		return Merchant{}, errors.Wrap(err, "can`t unmarshal credentials file")
+	}
+	m := Merchant{ID: strings.TrimSpace(creds.ID), Pass: creds.Pass}
+	if m.ID == "" || m.Pass == "" {
+		return Merchant{}, errors.Errorf("empty id or pass in credentials file %s", f.Path)
+	}
+	return m, nil
+}
+
+// ReloadingCredentials is a CredentialsProvider that caches credentials of another provider
+// and reloads them after given interval. Credentials can be rotated while Client performs calls:
+// concurrent calls get either previous or new credentials, never a mix of them.
+// If a reload fails previous credentials are used until the interval passes since the failed reload.
+// It is safe for concurrent use
+type ReloadingCredentials struct {
+	// loaded is a time credentials were loaded, attempted is a time of the last reload
+	loaded, attempted time.Time
+	p                 CredentialsProvider
+	now               func() time.Time
+	creds             Merchant
+	interval          time.Duration
+	// reloadMu serializes reloads, mu guards cached credentials
+	reloadMu sync.Mutex
+	mu       sync.RWMutex
+}
+
+// NewReloadingCredentials returns ReloadingCredentials of p reloaded every interval.
+// Credentials are reloaded on every call if interval is not positive
+func NewReloadingCredentials(p CredentialsProvider, interval time.Duration) *ReloadingCredentials {
+	return &ReloadingCredentials{p: p, interval: interval, now: time.Now}
+}
+
+// Credentials implements CredentialsProvider interface for r.
+// Previously loaded credentials are returned if reload failed
+func (r *ReloadingCredentials) Credentials(ctx context.Context) (Merchant, error) {
+	if m, ok := r.cached(); ok {
+		return m, nil
+	}
+
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	// credentials could be reloaded while waiting for reloadMu
+	if m, ok := r.cached(); ok {
+		return m, nil
+	}
+	if err := r.reload(ctx); err != nil {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		if r.loaded.IsZero() {
+			return Merchant{}, err
+		}
+		return r.creds, nil
+	}
+	m, _ := r.cached()
+	return m, nil
+}
+
+// Format implements fmt.Formatter for r, credentials are formatted with masked password
+func (r *ReloadingCredentials) Format(f fmt.State, verb rune) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fmt.Fprintf(f, "p24.ReloadingCredentials{Interval:%s Credentials:"+formatVerb(f, verb)+"}", r.interval, r.creds)
+}
+
+// Reload loads credentials from underlying provider immediately.
+// Previously loaded credentials are kept if it returns an error
+func (r *ReloadingCredentials) Reload(ctx context.Context) error {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	return r.reload(ctx)
+}
+
+// reload loads credentials, r.reloadMu must be held
+func (r *ReloadingCredentials) reload(ctx context.Context) error {
+	m, err := r.p.Credentials(ctx)
+	now := r.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempted = now
+	if err != nil {
+		return errors.Wrap(err, "can`t reload merchant credentials")
+	}
+	r.creds, r.loaded = m, now
+	return nil
+}
+
+// cached returns loaded credentials and whether they are not expired.
+// Credentials are not expired until the interval passes since the last reload even if it failed
+func (r *ReloadingCredentials) cached() (Merchant, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.loaded.IsZero() || r.interval <= 0 || r.now().Sub(r.attempted) >= r.interval {
+		return r.creds, false
+	}
+	return r.creds, true
+}
+
+// NewSignedRequest returns Request with MerchantSign of reqData made by s
+func NewSignedRequest(ctx context.Context, s Signer, reqData RequestData) (Request, error) {
+	req := newRequest(reqData)
+	data, err := requestData(req)
+	if err != nil {
+		return Request{}, err
+	}
+	if req.MerchantSign, err = s.SignData(ctx, data); err != nil {
+		return Request{}, errors.Wrap(err, "can`t sign request")
+	}
+	return req, nil
+}
+
+// VerifySignature returns an error matching ErrInvalidSignature if dataSign is not a signature of data made by s
+func VerifySignature(ctx context.Context, s Signer, data []byte, dataSign MerchantSign) error {
+	expected, err := s.SignData(ctx, data)
+	if err != nil {
+		return errors.Wrap(err, "can`t sign response data")
+	}
+	return verifySign(expected, dataSign)
+}
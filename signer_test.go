@@ -0,0 +1,264 @@
+package p24
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvCredentials_Credentials(t *testing.T) {
+	t.Setenv(DefaultEnvMerchantID, "id")
+	t.Setenv(DefaultEnvMerchantPass, "pass")
+	t.Setenv("MY_ID", "my id")
+
+	m, err := EnvCredentials{}.Credentials(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, Merchant{"id", "pass"}, m)
+
+	_, err = EnvCredentials{IDKey: "MY_ID", PassKey: "MY_PASS"}.Credentials(context.Background())
+	require.EqualError(t, err, "empty MY_ID or MY_PASS environment variable")
+}
+
+func TestFileCredentials_Credentials(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		content  string
+		errMsg   string
+		expected Merchant
+	}{
+		{content: `{"id": " id\n", "pass": "pass"}`, expected: Merchant{"id", "pass"}},
+		{content: `{"id": "id"}`, errMsg: "empty id or pass in credentials file"},
+		{content: `{`, errMsg: "can`t unmarshal credentials file: unexpected end of JSON input"},
+		{errMsg: "can`t read credentials file"},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			path := filepath.Join(dir, strconv.Itoa(i)+".json")
+			if c.content != "" {
+				require.NoError(t, os.WriteFile(path, []byte(c.content), 0o600))
+			}
+			m, err := FileCredentials{path}.Credentials(context.Background())
+			if c.errMsg != "" {
+				require.ErrorContains(t, err, c.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.expected, m)
+		})
+	}
+}
+
+// rotatingCredentials is a CredentialsProvider with rotated password
+type rotatingCredentials struct {
+	err   error
+	pass  string
+	loads int
+	mu    sync.Mutex
+}
+
+func (r *rotatingCredentials) Credentials(context.Context) (Merchant, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loads++
+	if r.err != nil {
+		return Merchant{}, r.err
+	}
+	return Merchant{"id", r.pass}, nil
+}
+
+func (r *rotatingCredentials) rotate(pass string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pass, r.err = pass, err
+}
+
+func TestReloadingCredentials_Credentials(t *testing.T) {
+	ctx := context.Background()
+	p := &rotatingCredentials{err: errors.New("unavailable")}
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewReloadingCredentials(p, time.Minute)
+	r.now = func() time.Time { return now }
+
+	_, err := r.Credentials(ctx)
+	require.EqualError(t, err, "can`t reload merchant credentials: unavailable")
+
+	p.rotate("pass1", nil)
+	m, err := r.Credentials(ctx)
+	require.NoError(t, err)
+	require.Equal(t, Merchant{"id", "pass1"}, m)
+
+	// cached until interval is passed
+	p.rotate("pass2", nil)
+	m, _ = r.Credentials(ctx)
+	require.Equal(t, "pass1", m.Pass)
+	require.Equal(t, 2, p.loads)
+
+	now = now.Add(time.Minute)
+	m, _ = r.Credentials(ctx)
+	require.Equal(t, "pass2", m.Pass)
+
+	// previous credentials are kept on reload failure and the reload is not repeated until interval is passed
+	p.rotate("", errors.New("unavailable"))
+	now = now.Add(time.Minute)
+	m, err = r.Credentials(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "pass2", m.Pass)
+	require.Equal(t, 4, p.loads)
+	now = now.Add(time.Minute - time.Second)
+	m, err = r.Credentials(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "pass2", m.Pass)
+	require.Equal(t, 4, p.loads)
+	now = now.Add(time.Second)
+	_, _ = r.Credentials(ctx)
+	require.Equal(t, 5, p.loads)
+	require.EqualError(t, r.Reload(ctx), "can`t reload merchant credentials: unavailable")
+
+	p.rotate("pass3", nil)
+	require.NoError(t, r.Reload(ctx))
+	m, _ = r.Credentials(ctx)
+	require.Equal(t, "pass3", m.Pass)
+}
+
+// blockingCredentials is a CredentialsProvider that fails after release is closed
+type blockingCredentials struct {
+	release chan struct{}
+	loads   int32
+}
+
+func (b *blockingCredentials) Credentials(context.Context) (Merchant, error) {
+	if atomic.AddInt32(&b.loads, 1) == 1 {
+		return Merchant{"id", "pass"}, nil
+	}
+	<-b.release
+	return Merchant{}, errors.New("unavailable")
+}
+
+func TestReloadingCredentials_Credentials_FailingReload(t *testing.T) {
+	p := &blockingCredentials{release: make(chan struct{})}
+	var mu sync.Mutex
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewReloadingCredentials(p, time.Minute)
+	r.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	_, err := r.Credentials(context.Background())
+	require.NoError(t, err)
+	mu.Lock()
+	now = now.Add(time.Minute)
+	mu.Unlock()
+
+	results := make(chan Merchant, 20)
+	for i := 0; i < cap(results); i++ {
+		go func() {
+			m, err := r.Credentials(context.Background())
+			if err != nil {
+				m = Merchant{}
+			}
+			results <- m
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(p.release)
+	for i := 0; i < cap(results); i++ {
+		require.Equal(t, Merchant{"id", "pass"}, <-results)
+	}
+	// calls waiting for the failed reload and later calls use previous credentials without reloads
+	for i := 0; i < 10; i++ {
+		_, err := r.Credentials(context.Background())
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&p.loads))
+}
+
+func TestNewSignedRequest(t *testing.T) {
+	m := Merchant{"id", "pass"}
+	reqData := RequestData{CommonOpts: CommonOpts{Oper: "cmt"}}
+
+	req, err := NewSignedRequest(context.Background(), m, reqData)
+	require.NoError(t, err)
+	require.Equal(t, NewRequest(m, reqData), req)
+
+	req, err = NewSignedRequest(context.Background(), NewCredentialsSigner(m), reqData)
+	require.NoError(t, err)
+	require.Equal(t, NewRequest(m, reqData), req)
+
+	_, err = NewSignedRequest(context.Background(), NewCredentialsSigner(EnvCredentials{IDKey: "NO_ID"}), reqData)
+	require.EqualError(t, err, "can`t sign request: can`t get merchant credentials: empty NO_ID or P24_MERCHANT_PASS environment variable")
+}
+
+func TestVerifySignature(t *testing.T) {
+	m := Merchant{"id", "pass"}
+	data := []byte("data")
+	require.NoError(t, VerifySignature(context.Background(), m, data, m.Sign(data)))
+	require.EqualError(t, VerifySignature(context.Background(), m, data, MerchantSign{"id", "sign"}), "invalid signature")
+}
+
+func TestClient_Signer_Rotation(t *testing.T) {
+	passwords := []string{"pass0", "pass1", "pass2", "pass3"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		require.NoError(t, xml.NewDecoder(r.Body).Decode(&req))
+		// p24 responds with a signature made by the password of request
+		for _, pass := range passwords {
+			m := Merchant{"id", pass}
+			if NewRequest(m, req.Data).MerchantSign == req.MerchantSign {
+				data := `<oper>cmt</oper><info><cardbalance><card><card_number>1234567890123456</card_number></card><bal_date>01.01.21 10:00</bal_date></cardbalance></info>`
+				sign := m.Sign([]byte(data))
+				_, _ = fmt.Fprintf(w, `<response version="1.0"><merchant><id>%s</id><signature>%s</signature></merchant><data>%s</data></response>`,
+					sign.ID, sign.Sign, data)
+				return
+			}
+		}
+		_, _ = w.Write([]byte(`<response><data><error message="invalid signature"/></data></response>`))
+	}))
+	defer srv.Close()
+
+	creds, rotations := &rotatingCredentials{pass: passwords[0]}, int32(0)
+	cli, err := NewClient(ClientOpts{
+		HTTP:    http.DefaultClient,
+		BaseURL: srv.URL + "/",
+		Signer:  NewCredentialsSigner(creds),
+		Middlewares: []Middleware{func(next Handler) Handler {
+			return func(ctx context.Context, call Call, resp *Response) error {
+				// rotate credentials in the middle of the call
+				creds.rotate(passwords[atomic.AddInt32(&rotations, 1)%int32(len(passwords))], nil)
+				return next(ctx, call, resp)
+			}
+		}},
+	})
+	require.NoError(t, err)
+
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			creds.rotate(passwords[i%len(passwords)], nil)
+			_, err := cli.GetCardBalance(context.Background(), BalanceOpts{CardNumber: "1234567890123456"})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build go1.21
+
+package p24
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SlogAdapter adapts log/slog Handler to StructuredLogger
+type SlogAdapter struct {
+	Handler slog.Handler
+}
+
+// Log implements StructuredLogger interface for a
+func (a SlogAdapter) Log(ctx context.Context, level Level, msg string, fields ...Field) {
+	if !a.Handler.Enabled(ctx, slog.Level(level)) {
+		return
+	}
+
+	r := slog.NewRecord(time.Now(), slog.Level(level), msg, 0)
+	for _, f := range fields {
+		r.AddAttrs(slog.Any(f.Key, f.Value))
+	}
+	_ = a.Handler.Handle(ctx, r)
+}
+
+// LogValue implements slog.LogValuer for m, the password is masked
+func (m Merchant) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("id", m.ID), slog.String("pass", m.redactedPass()))
+}
@@ -0,0 +1,45 @@
+//go:build go1.21
+
+package p24
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogAdapter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := SlogAdapter{slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})}
+
+	log.Log(context.Background(), LevelDebug, "debug msg")
+	require.Empty(t, buf.String())
+
+	log.Log(context.Background(), LevelWarn, "warn msg", F("endpoint", EndpointStatements), F("attempt", 2))
+	require.Equal(t, "level=WARN msg=\"warn msg\" endpoint=statements attempt=2\n", buf.String())
+}
+
+func TestMerchant_LogValue(t *testing.T) {
+	buf := &bytes.Buffer{}
+	log := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	log.Info("msg", "merchant", Merchant{"id", "pass"})
+	require.Equal(t, `{"level":"INFO","msg":"msg","merchant":{"id":"id","pass":"[REDACTED]"}}`+"\n", buf.String())
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/xml"
 	"fmt"
-	"net/http"
 	"strings"
 	"time"
 
@@ -12,7 +11,6 @@ import (
 )
 
 const (
-	statementsAPIURL         = "https://api.privatbank.ua/p24api/rest_fiz"
 	statementsReqTimeLayout  = "02.01.2006"
 	statementsRespDateLayout = "2006-01-02"
 	statementsRespTimeLayout = "15:04:05"
@@ -31,12 +29,12 @@ type StatementsOpts struct {
 // P24 statements api provide date range with max 90 days
 func (r StatementsOpts) Validate() error {
 	if r.StartDate.Unix() > r.EndDate.Unix() {
-		return errors.New("date range should be with start date <= end date")
+		return &classError{errors.New("date range should be with start date <= end date"), ErrInvalidDateRange}
 	}
 
 	// check date range <= 90 days
 	if r.EndDate.Sub(r.StartDate) > 90*24*time.Hour {
-		return errors.New("date range should be no longer than 90 days")
+		return &classError{errors.New("date range should be no longer than 90 days"), ErrInvalidDateRange}
 	}
 
 	if err := CheckCardNumber(r.CardNumber); err != nil {
@@ -119,7 +117,25 @@ func (c *Client) GetStatements(ctx context.Context, opts StatementsOpts) (Statem
 		return Statements{}, errors.Wrap(err, "invalid request options")
 	}
 
-	reqData := RequestData{
+	reqData := statementsRequestData(opts)
+
+	type info struct {
+		Statements Statements `xml:"statements"`
+	}
+	check := func(resp Response) error {
+		return checkStatements(opts, resp.Data.Info.(info).Statements)
+	}
+	resp := Response{Data: ResponseData{Info: info{}}}
+	if err := c.do(ctx, SpanGetStatements, EndpointStatements, reqData, check, nil, &resp); err != nil {
+		return Statements{}, err
+	}
+
+	return resp.Data.Info.(info).Statements, nil
+}
+
+// statementsRequestData returns p24 statements request data for opts
+func statementsRequestData(opts StatementsOpts) RequestData {
+	return RequestData{
 		CommonOpts: opts.CommonOpts,
 		Payment: struct {
 			ID   string "xml:\"id,attr\""
@@ -147,14 +163,4 @@ func (c *Client) GetStatements(ctx context.Context, opts StatementsOpts) (Statem
 			},
 		},
 	}
-
-	type info struct {
-		Statements Statements `xml:"statements"`
-	}
-	resp := Response{Data: ResponseData{Info: info{}}}
-	if err := c.DoContext(ctx, statementsAPIURL, http.MethodPost, NewRequest(c.merchant, reqData), &resp); err != nil {
-		return Statements{}, err
-	}
-
-	return resp.Data.Info.(info).Statements, nil
 }
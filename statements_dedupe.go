@@ -0,0 +1,50 @@
+package p24
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Fingerprint returns a deterministic identity of s derived from card, date, appcode,
+// terminal, amounts and rest. The same transaction returned by different p24 responses has
+// the same fingerprint. Identical purchases of a card differ in rest after each of them,
+// so they have different fingerprints
+func (s Statement) Fingerprint() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q|%d|%q|%q|%q|%d|%q|%d|%q|%d",
+		s.Card, s.Date.Unix(), s.Appcode, s.Terminal,
+		s.Amount.Currency, s.Amount.Amount, s.CardAmount.Currency, s.CardAmount.Amount, s.Rest.Currency, s.Rest.Amount)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Dedupe merges statements of results collapsing rows returned by more than one result,
+// for example by requests of overlapping date ranges. Rows with the same Fingerprint are
+// kept as many times as they occur in a single result at most. Rows are ordered by results and
+// their order in a result, Credit and Debet are recalculated by card amounts of the kept rows
+// and Status is the last non-empty status of results
+func Dedupe(results ...Statements) Statements {
+	var deduped Statements
+	kept := map[string]int{}
+	for _, result := range results {
+		if result.Status != "" {
+			deduped.Status = result.Status
+		}
+
+		seen := make(map[string]int, len(result.Statements))
+		for _, s := range result.Statements {
+			fp := s.Fingerprint()
+			if seen[fp]++; seen[fp] <= kept[fp] {
+				continue
+			}
+			kept[fp]++
+			deduped.Statements = append(deduped.Statements, s)
+			if amount := s.CardAmount.Amount; amount < 0 {
+				deduped.Debet -= amount
+			} else {
+				deduped.Credit += amount
+			}
+		}
+	}
+	return deduped
+}
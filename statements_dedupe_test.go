@@ -0,0 +1,86 @@
+package p24
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatement_Fingerprint(t *testing.T) {
+	s := Statement{
+		Card:        "1234567890123456",
+		Appcode:     "12345",
+		Date:        time.Date(2021, 1, 1, 5, 5, 5, 0, kievLocation),
+		Terminal:    "PrivatBank, 123",
+		Description: "coffee",
+		Amount:      Funds{"UAH", -550},
+		CardAmount:  Funds{"UAH", -550},
+		Rest:        Funds{"UAH", 1000},
+	}
+	fp := s.Fingerprint()
+	require.Len(t, fp, 64)
+
+	same := s
+	same.Date = s.Date.UTC()
+	same.Description = "coffee shop"
+	require.Equal(t, fp, same.Fingerprint())
+
+	changes := []func(s *Statement){
+		func(s *Statement) { s.Card = "1234567890123457" },
+		func(s *Statement) { s.Appcode = "" },
+		func(s *Statement) { s.Date = s.Date.Add(time.Second) },
+		func(s *Statement) { s.Terminal = "PrivatBank, 12" },
+		func(s *Statement) { s.Amount.Currency = "USD" },
+		func(s *Statement) { s.Amount.Amount = -551 },
+		func(s *Statement) { s.CardAmount.Amount = 550 },
+		func(s *Statement) { s.Rest.Amount = 450 },
+		// fields must not be concatenated ambiguously
+		func(s *Statement) { s.Appcode, s.Terminal = "12345|", "PrivatBank" },
+	}
+	for i, change := range changes {
+		change := change
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			other := s
+			change(&other)
+			require.NotEqual(t, fp, other.Fingerprint())
+		})
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	date := time.Date(2021, 1, 1, 10, 0, 0, 0, kievLocation)
+	coffee := func(rest Amount) Statement {
+		return Statement{Card: "1234567890123456", Date: date, Amount: Funds{"UAH", -50}, CardAmount: Funds{"UAH", -50}, Rest: Funds{"UAH", rest}}
+	}
+	salary := Statement{Card: "1234567890123456", Date: date.AddDate(0, 0, 1), Appcode: "1", Amount: Funds{"UAH", 1000}, CardAmount: Funds{"UAH", 1000}, Rest: Funds{"UAH", 1900}}
+	// identical p24 rows, for example a purchase that was reversed and repeated
+	twin := Statement{Card: "1234567890123456", Date: date.AddDate(0, 0, 2), Amount: Funds{"UAH", -10}, CardAmount: Funds{"UAH", -10}, Rest: Funds{"UAH", 1890}}
+
+	cases := []struct {
+		results  []Statements
+		expected Statements
+	}{
+		{nil, Statements{}},
+		{
+			[]Statements{{Status: "excellent", Statements: []Statement{coffee(950), coffee(900)}}},
+			Statements{Status: "excellent", Debet: 100, Statements: []Statement{coffee(950), coffee(900)}},
+		},
+		{
+			[]Statements{
+				{Status: "a", Statements: []Statement{coffee(950), coffee(900)}},
+				{Status: "b", Statements: []Statement{coffee(900), salary, twin, twin}},
+				{Statements: []Statement{twin, salary, twin, twin}},
+			},
+			Statements{Status: "b", Credit: 1000, Debet: 130, Statements: []Statement{coffee(950), coffee(900), salary, twin, twin, twin}},
+		},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require.Equal(t, c.expected, Dedupe(c.results...))
+		})
+	}
+}
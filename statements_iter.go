@@ -0,0 +1,222 @@
+package p24
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// StatementIterator iterates over statements of a date range of any length.
+// The range is split by SplitStatementsRange and windows are fetched one by one when
+// statements of the previous window are exhausted. Statements of a window are decoded
+// from the verified p24 response one at a time, so memory usage doesn`t depend on the range length.
+// Rows of window boundaries are de-duplicated like by GetStatementsRange.
+// Rows are checked one by one in strict verification, so a mismatched row fails the iteration
+// after rows preceding it are yielded.
+//
+//	it := client.IterateStatements(ctx, opts)
+//	for it.Next() {
+//		s := it.Statement()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type StatementIterator struct {
+	ctx     context.Context
+	c       *Client
+	windows []StatementsOpts
+	// fetched is a number of fetched windows
+	fetched int
+	dec     *statementDecoder
+	dedupe  windowDedupe
+	cur     Statement
+	err     error
+}
+
+// IterateStatements returns StatementIterator over statements of opts date range of any length
+func (c *Client) IterateStatements(ctx context.Context, opts StatementsOpts) *StatementIterator {
+	it := &StatementIterator{ctx: ctx, c: c}
+	if err := validateRange(opts); err != nil {
+		it.err = errors.Wrap(err, "invalid request options")
+		return it
+	}
+	it.windows = SplitStatementsRange(opts)
+	return it
+}
+
+// Next advances it to the next statement, which will then be available through Statement.
+// It returns false when there are no more statements or an error occurred, see Err
+func (it *StatementIterator) Next() bool {
+	for it.err == nil {
+		if it.dec == nil && !it.fetch() {
+			return false
+		}
+
+		s, err := it.dec.next()
+		if err == io.EOF {
+			it.dec = nil
+			continue
+		}
+		window := it.windows[it.fetched-1]
+		if err != nil {
+			err = &classError{errors.Wrap(err, "can`t unmarshal xml response"), ErrDecode}
+		} else if it.c.strict {
+			err = checkStatements(window, Statements{Statements: []Statement{s}})
+		}
+		if err != nil {
+			it.err = &RangeError{Err: err, Window: window, Fetched: it.fetched - 1, Windows: len(it.windows)}
+			return false
+		}
+
+		if it.dedupe.duplicate(s) {
+			continue
+		}
+		it.cur = s
+		return true
+	}
+	return false
+}
+
+// fetch fetches the next window. It returns false if there are no more windows or an error occurred
+func (it *StatementIterator) fetch() bool {
+	if it.fetched == len(it.windows) {
+		return false
+	}
+
+	window := it.windows[it.fetched]
+	data, err := it.c.getStatementsStream(it.ctx, window)
+	if err != nil {
+		it.err = &RangeError{Err: err, Window: window, Fetched: it.fetched, Windows: len(it.windows)}
+		return false
+	}
+	it.fetched++
+	it.dec = newStatementDecoder(data)
+	it.dedupe.nextWindow()
+	return true
+}
+
+// Statement returns the current statement
+func (it *StatementIterator) Statement() Statement { return it.cur }
+
+// Err returns an error occurred during iteration if any.
+// It is *RangeError if a window failed
+func (it *StatementIterator) Err() error { return it.err }
+
+// EachStatement calls fn for each statement of opts date range of any length
+// in the order of StatementIterator. It stops on the first error of fn and returns it
+func (c *Client) EachStatement(ctx context.Context, opts StatementsOpts, fn func(Statement) error) error {
+	it := c.IterateStatements(ctx, opts)
+	for it.Next() {
+		if err := fn(it.Statement()); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// getStatementsStream performs p24 statements api call for opts and returns raw verified '<data>' content.
+// Rows of '<statements>' are not decoded, see statementDecoder
+func (c *Client) getStatementsStream(ctx context.Context, opts StatementsOpts) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid request options")
+	}
+
+	type info struct {
+		Statements statementsMark `xml:"statements"`
+	}
+	var data []byte
+	resp := Response{Data: ResponseData{Info: info{}}}
+	if err := c.do(ctx, SpanGetStatements, EndpointStatements, statementsRequestData(opts), nil, &data, &resp); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// statementsMark is true if p24 response has '<statements>' element
+type statementsMark bool
+
+// UnmarshalXML implements xml.Unmarshaler interface for m. It skips the element without decoding its rows
+func (m *statementsMark) UnmarshalXML(d *xml.Decoder, _ xml.StartElement) error {
+	*m = true
+	return d.Skip()
+}
+
+// statementDecoder decodes '<statement>' elements of '<info><statements>' element
+// of raw '<data>' content one by one
+type statementDecoder struct {
+	d       *xml.Decoder
+	started bool
+}
+
+func newStatementDecoder(data []byte) *statementDecoder {
+	return &statementDecoder{d: xml.NewDecoder(bytes.NewReader(data))}
+}
+
+// start skips tokens of data up to the first row of '<info><statements>' element
+func (sd *statementDecoder) start() error {
+	for depth := 0; ; {
+		token, err := sd.d.Token()
+		if err == io.EOF {
+			return errors.New("'<statements>' tag not found")
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case depth == 0 && t.Name.Local == "info":
+				depth++
+			case depth == 1 && t.Name.Local == "statements":
+				return nil
+			default:
+				if err := sd.d.Skip(); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// next returns the next statement or io.EOF if there are no more statements
+func (sd *statementDecoder) next() (Statement, error) {
+	if !sd.started {
+		if err := sd.start(); err != nil {
+			return Statement{}, err
+		}
+		sd.started = true
+	}
+
+	for {
+		token, err := sd.d.Token()
+		if err != nil {
+			return Statement{}, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "statement" {
+				if err := sd.d.Skip(); err != nil {
+					return Statement{}, err
+				}
+				continue
+			}
+			var s Statement
+			if err := sd.d.DecodeElement(&s, &t); err != nil {
+				return Statement{}, err
+			}
+			return s, nil
+		case xml.EndElement:
+			// end of '<statements>' element
+			return Statement{}, io.EOF
+		}
+	}
+}
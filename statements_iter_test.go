@@ -0,0 +1,169 @@
+package p24
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_IterateStatements(t *testing.T) {
+	m := Merchant{"id", "pass"}
+	opts := StatementsOpts{
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, kievLocation),
+		EndDate:    time.Date(2021, 12, 31, 0, 0, 0, 0, kievLocation),
+		CardNumber: "1234567890123456",
+	}
+
+	t.Run("Range", func(t *testing.T) {
+		srv := statementsServer(t, m, nil)
+		defer srv.Close()
+		cli, err := NewClient(ClientOpts{HTTP: http.DefaultClient, BaseURL: srv.URL + "/", Merchant: m})
+		require.NoError(t, err)
+		expected, err := cli.GetStatementsRange(context.Background(), StatementsRangeOpts{StatementsOpts: opts})
+		require.NoError(t, err)
+
+		var actual []Statement
+		it := cli.IterateStatements(context.Background(), opts)
+		for it.Next() {
+			actual = append(actual, it.Statement())
+		}
+		require.NoError(t, it.Err())
+		require.Equal(t, expected.Statements, actual)
+		require.False(t, it.Next())
+	})
+
+	t.Run("Overlap", func(t *testing.T) {
+		// windows return rows of both adjacent days, so each boundary day is returned by two windows
+		srv := statementsOverlapServer(t, m, nil, 1)
+		defer srv.Close()
+		cli, err := NewClient(ClientOpts{HTTP: http.DefaultClient, BaseURL: srv.URL + "/", Merchant: m})
+		require.NoError(t, err)
+		expected, err := cli.GetStatementsRange(context.Background(), StatementsRangeOpts{StatementsOpts: opts})
+		require.NoError(t, err)
+		// days of the range with the previous and the next days
+		require.Len(t, expected.Statements, 367)
+		require.Equal(t, Amount(367*100), expected.Debet)
+
+		var actual []Statement
+		require.NoError(t, cli.EachStatement(context.Background(), opts, func(s Statement) error {
+			actual = append(actual, s)
+			return nil
+		}))
+		require.Equal(t, expected.Statements, actual)
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		srv := statementsServer(t, m, func(sd string) bool { return sd == "30.06.2021" })
+		defer srv.Close()
+		cli, err := NewClient(ClientOpts{HTTP: http.DefaultClient, BaseURL: srv.URL + "/", Merchant: m})
+		require.NoError(t, err)
+
+		n := 0
+		it := cli.IterateStatements(context.Background(), opts)
+		for it.Next() {
+			n++
+		}
+		require.Equal(t, 181, n)
+		require.ErrorIs(t, it.Err(), ErrHTTPStatus)
+		var rangeErr *RangeError
+		require.True(t, errors.As(it.Err(), &rangeErr))
+		require.Equal(t, 2, rangeErr.Fetched)
+		require.Equal(t, time.Date(2021, 6, 30, 0, 0, 0, 0, kievLocation), rangeErr.Window.StartDate)
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data := `<oper>cmt</oper><info><statements status="excellent" credit="0.0" debet="1.00">` +
+				`<statement card="1234567890123456" appcode="1" trandate="2021-01-01" trantime="12:00:00" amount="-1.00 UAH" cardamount="-1.00 UAH" rest="1.00 UAH" terminal="" description=""/>` +
+				`<statement card="1234567890123456" appcode="2" trandate="2022-01-01" trantime="12:00:00" amount="-1.00 UAH" cardamount="-1.00 UAH" rest="1.00 UAH" terminal="" description=""/>` +
+				`</statements></info>`
+			_, _ = w.Write([]byte(signedResponse(m, m.ID, data)))
+		}))
+		defer srv.Close()
+		cli, err := NewClient(ClientOpts{HTTP: http.DefaultClient, BaseURL: srv.URL + "/", Merchant: m, StrictVerify: true})
+		require.NoError(t, err)
+
+		it := cli.IterateStatements(context.Background(), StatementsOpts{StartDate: opts.StartDate, EndDate: opts.StartDate, CardNumber: opts.CardNumber})
+		require.True(t, it.Next())
+		require.Equal(t, "1", it.Statement().Appcode)
+		require.False(t, it.Next())
+		var mismatchErr *MismatchError
+		require.True(t, errors.As(it.Err(), &mismatchErr))
+		require.Equal(t, MismatchDate, mismatchErr.Field)
+	})
+
+	t.Run("InvalidOpts", func(t *testing.T) {
+		it := (&Client{}).IterateStatements(context.Background(), StatementsOpts{StartDate: opts.EndDate, EndDate: opts.StartDate, CardNumber: opts.CardNumber})
+		require.False(t, it.Next())
+		require.ErrorIs(t, it.Err(), ErrInvalidDateRange)
+	})
+}
+
+func TestClient_EachStatement(t *testing.T) {
+	m := Merchant{"id", "pass"}
+	srv := statementsServer(t, m, nil)
+	defer srv.Close()
+	cli, err := NewClient(ClientOpts{HTTP: http.DefaultClient, BaseURL: srv.URL + "/", Merchant: m})
+	require.NoError(t, err)
+	opts := StatementsOpts{
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, kievLocation),
+		EndDate:    time.Date(2021, 12, 31, 0, 0, 0, 0, kievLocation),
+		CardNumber: "1234567890123456",
+	}
+
+	var total Amount
+	require.NoError(t, cli.EachStatement(context.Background(), opts, func(s Statement) error {
+		total += s.CardAmount.Amount
+		return nil
+	}))
+	require.Equal(t, Amount(-366*100), total)
+
+	stop, n := errors.New("stop"), 0
+	err = cli.EachStatement(context.Background(), opts, func(s Statement) error {
+		if n++; n == 100 {
+			return stop
+		}
+		return nil
+	})
+	require.Equal(t, stop, err)
+	require.Equal(t, 100, n)
+}
+
+func Test_statementDecoder(t *testing.T) {
+	cases := []struct {
+		data     string
+		expected []string
+		err      bool
+	}{
+		{`<oper>cmt</oper><info><statements status="excellent" credit="0" debet="0"/></info>`, nil, false},
+		{`<oper>cmt</oper><info><!-- comment --><other><statements><statement appcode="x"/></statements></other>` +
+			`<statements><!-- comment --><statement appcode="1" trandate="2021-01-01" trantime="12:00:00"/><other><statement appcode="x"/></other>` +
+			`<statement appcode="2" trandate="2021-01-02" trantime="12:00:00"></statement></statements></info>`, []string{"1", "2"}, false},
+		{`<info><statements><statement appcode="1" trandate="2021-01-01" trantime="12:00:00"/><statement appcode="2" trandate="bad"/></statements></info>`, []string{"1"}, true},
+		{`<statements><statement appcode="1" trandate="2021-01-01" trantime="12:00:00"/></statements><info/>`, nil, true},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			dec := newStatementDecoder([]byte(c.data))
+			var appcodes []string
+			for {
+				s, err := dec.next()
+				if err != nil {
+					require.Equal(t, c.err, !errors.Is(err, io.EOF))
+					break
+				}
+				appcodes = append(appcodes, s.Appcode)
+			}
+			require.Equal(t, c.expected, appcodes)
+		})
+	}
+}
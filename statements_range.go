@@ -0,0 +1,220 @@
+package p24
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// MaxStatementsWindowDays is a max number of calendar days in a statements window of GetStatementsRange
+const MaxStatementsWindowDays = 90
+
+// StatementsRangeOpts is a full set of all parameters of GetStatementsRange call.
+// Date range of StatementsOpts can be of any length
+type StatementsRangeOpts struct {
+	StatementsOpts
+
+	// Concurrency is a max number of concurrently fetched windows.
+	// Windows are fetched sequentially if less than 2
+	Concurrency int
+}
+
+// RangeError reports a failed window of GetStatementsRange call
+type RangeError struct {
+	Err error
+	// Window is the failed window
+	Window StatementsOpts
+	// Fetched is a number of successfully fetched windows preceding the failed one
+	Fetched int
+	// Windows is a number of all windows of the range
+	Windows int
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("statements window %s-%s failed, %d of %d windows fetched: %v",
+		e.Window.StartDate.Format(statementsReqTimeLayout), e.Window.EndDate.Format(statementsReqTimeLayout),
+		e.Fetched, e.Windows, e.Err)
+}
+
+func (e *RangeError) Unwrap() error { return e.Err }
+
+func (e *RangeError) Cause() error { return e.Err }
+
+// SplitStatementsRange splits date range of opts into windows of at most MaxStatementsWindowDays
+// calendar days aligned to Kyiv midnight. Windows are ordered by dates and don`t overlap
+func SplitStatementsRange(opts StatementsOpts) []StatementsOpts {
+	start, end := kievDay(opts.StartDate), kievDay(opts.EndDate)
+	var windows []StatementsOpts
+	for from := start; !from.After(end); from = from.AddDate(0, 0, MaxStatementsWindowDays) {
+		to := from.AddDate(0, 0, MaxStatementsWindowDays-1)
+		if to.After(end) {
+			to = end
+		}
+		window := opts
+		window.StartDate, window.EndDate = from, to
+		windows = append(windows, window)
+	}
+	return windows
+}
+
+// kievDay returns Kyiv midnight of t day in Kyiv
+func kievDay(t time.Time) time.Time {
+	y, m, d := t.In(kievLocation).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, kievLocation)
+}
+
+// GetStatementsRange returns Statements for date range of any length.
+// The range is split by SplitStatementsRange, windows are fetched by GetStatements
+// and merged in order of dates with de-duplicated rows of window boundaries.
+// If a window fails it returns merged Statements of windows preceding the failed one and *RangeError
+func (c *Client) GetStatementsRange(ctx context.Context, opts StatementsRangeOpts) (Statements, error) {
+	if err := validateRange(opts.StatementsOpts); err != nil {
+		return Statements{}, errors.Wrap(err, "invalid request options")
+	}
+
+	windows := SplitStatementsRange(opts.StatementsOpts)
+	results, failed, err := c.fetchWindows(ctx, windows, opts.Concurrency)
+	merged := mergeStatements(results[:failed])
+	if err != nil {
+		return merged, &RangeError{Err: err, Window: windows[failed], Fetched: failed, Windows: len(windows)}
+	}
+	return merged, nil
+}
+
+// validateRange validates opts of a date range of any length
+func validateRange(opts StatementsOpts) error {
+	if opts.StartDate.After(opts.EndDate) {
+		return &classError{errors.New("date range should be with start date <= end date"), ErrInvalidDateRange}
+	}
+	if err := CheckCardNumber(opts.CardNumber); err != nil {
+		return errors.Wrap(err, "invalid card number")
+	}
+	return nil
+}
+
+// fetchWindows fetches statements of windows with given concurrency.
+// It returns index of the first failed window and its error or len(windows).
+// Windows following the failed one are canceled or not fetched at all
+func (c *Client) fetchWindows(ctx context.Context, windows []StatementsOpts, concurrency int) ([]Statements, int, error) {
+	results, errs := make([]Statements, len(windows)), make([]error, len(windows))
+	if concurrency < 2 {
+		for i, window := range windows {
+			if results[i], errs[i] = c.GetStatements(ctx, window); errs[i] != nil {
+				return results, i, errs[i]
+			}
+		}
+		return results, len(windows), nil
+	}
+
+	// a failed window cancels only the following windows, so preceding ones are still fetched
+	var (
+		mu      sync.Mutex
+		failed  = len(windows)
+		cancels = make([]context.CancelFunc, len(windows))
+	)
+	fail := func(i int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if i >= failed {
+			return
+		}
+		failed = i
+		for j := i + 1; j < len(windows); j++ {
+			if cancels[j] != nil {
+				cancels[j]()
+			}
+		}
+	}
+
+	sem, wg := make(chan struct{}, concurrency), sync.WaitGroup{}
+	for i := range windows {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			fail(i)
+			continue
+		}
+
+		mu.Lock()
+		if i > failed {
+			mu.Unlock()
+			<-sem
+			continue
+		}
+		var windowCtx context.Context
+		windowCtx, cancels[i] = context.WithCancel(ctx)
+		mu.Unlock()
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				cancels[i]()
+				<-sem
+				wg.Done()
+			}()
+			if results[i], errs[i] = c.GetStatements(windowCtx, windows[i]); errs[i] != nil {
+				fail(i)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if failed == len(windows) {
+		return results, failed, nil
+	}
+	return results, failed, errs[failed]
+}
+
+// mergeStatements merges windows statements in their order.
+// Rows of a window returned by the previous window are dropped with their amounts, see windowDedupe
+func mergeStatements(windows []Statements) Statements {
+	var merged Statements
+	var dedupe windowDedupe
+	for _, window := range windows {
+		merged.Status = window.Status
+		merged.Credit += window.Credit
+		merged.Debet += window.Debet
+
+		dedupe.nextWindow()
+		for _, s := range window.Statements {
+			if dedupe.duplicate(s) {
+				if amount := s.CardAmount.Amount; amount < 0 {
+					merged.Debet += amount
+				} else {
+					merged.Credit -= amount
+				}
+				continue
+			}
+			merged.Statements = append(merged.Statements, s)
+		}
+	}
+	return merged
+}
+
+// windowDedupe detects rows of boundary days returned by two consecutive windows.
+// A row of a window with Fingerprint of a row of the previous window is a duplicate,
+// each row of the previous window makes a duplicate of a single row
+type windowDedupe struct {
+	// prev and cur are numbers of rows of the previous and the current windows by their fingerprints
+	prev, cur map[string]int
+}
+
+// nextWindow starts rows of the next window
+func (d *windowDedupe) nextWindow() {
+	d.prev, d.cur = d.cur, map[string]int{}
+}
+
+// duplicate reports whether s of the current window is a duplicate of a row of the previous window
+func (d *windowDedupe) duplicate(s Statement) bool {
+	key := s.Fingerprint()
+	d.cur[key]++
+	if d.prev[key] > 0 {
+		d.prev[key]--
+		return true
+	}
+	return false
+}
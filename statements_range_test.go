@@ -0,0 +1,195 @@
+package p24
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitStatementsRange(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, kievLocation) }
+	cases := []struct {
+		start, end time.Time
+		expected   [][2]time.Time
+	}{
+		{day(2021, 1, 1), day(2021, 1, 1), [][2]time.Time{{day(2021, 1, 1), day(2021, 1, 1)}}},
+		{day(2021, 1, 1).Add(23 * time.Hour), day(2021, 1, 2).Add(time.Hour), [][2]time.Time{{day(2021, 1, 1), day(2021, 1, 2)}}},
+		{day(2021, 1, 1), day(2021, 3, 31), [][2]time.Time{{day(2021, 1, 1), day(2021, 3, 31)}}},
+		{day(2021, 1, 1), day(2021, 4, 1), [][2]time.Time{{day(2021, 1, 1), day(2021, 3, 31)}, {day(2021, 4, 1), day(2021, 4, 1)}}},
+		{
+			day(2021, 1, 1), day(2021, 12, 31),
+			[][2]time.Time{
+				{day(2021, 1, 1), day(2021, 3, 31)},
+				{day(2021, 4, 1), day(2021, 6, 29)},
+				{day(2021, 6, 30), day(2021, 9, 27)},
+				{day(2021, 9, 28), day(2021, 12, 26)},
+				{day(2021, 12, 27), day(2021, 12, 31)},
+			},
+		},
+		// 2021-01-01 23:30 UTC is 2021-01-02 in Kyiv
+		{
+			time.Date(2021, 1, 1, 23, 30, 0, 0, time.UTC), time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC),
+			[][2]time.Time{{day(2021, 1, 2), day(2021, 1, 3)}},
+		},
+		{day(2021, 1, 2), day(2021, 1, 1), nil},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			windows := SplitStatementsRange(StatementsOpts{StartDate: c.start, EndDate: c.end, CardNumber: "1234567890123456"})
+			var actual [][2]time.Time
+			for _, w := range windows {
+				require.Equal(t, "1234567890123456", w.CardNumber)
+				require.NoError(t, w.Validate())
+				actual = append(actual, [2]time.Time{w.StartDate, w.EndDate})
+			}
+			require.Equal(t, c.expected, actual)
+		})
+	}
+}
+
+// statementsServer returns test server of p24 statements api that responds
+// with a statement per day of requested range and a statement of the previous day
+func statementsServer(t *testing.T, m Merchant, fail func(sd string) bool) *httptest.Server {
+	t.Helper()
+	return statementsOverlapServer(t, m, fail, 0)
+}
+
+// statementsOverlapServer is like statementsServer, but it also responds
+// with statements of next days after requested range
+func statementsOverlapServer(t *testing.T, m Merchant, fail func(sd string) bool, next int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		require.NoError(t, xml.NewDecoder(r.Body).Decode(&req))
+		props := map[string]string{}
+		for _, p := range req.Data.Payment.Prop {
+			props[p.Name] = p.Value
+		}
+		if fail != nil && fail(props["sd"]) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		sd, err := time.ParseInLocation(statementsReqTimeLayout, props["sd"], kievLocation)
+		require.NoError(t, err)
+		ed, err := time.ParseInLocation(statementsReqTimeLayout, props["ed"], kievLocation)
+		require.NoError(t, err)
+
+		statements := Statements{Status: "excellent"}
+		for d := sd.AddDate(0, 0, -1); !d.After(ed.AddDate(0, 0, next)); d = d.AddDate(0, 0, 1) {
+			statements.Statements = append(statements.Statements, Statement{
+				Card:        props["card"],
+				Appcode:     d.Format("20060102"),
+				Date:        d.Add(12 * time.Hour),
+				Description: "test",
+				Amount:      Funds{"UAH", -100},
+				CardAmount:  Funds{"UAH", -100},
+				Rest:        Funds{"UAH", 1000},
+			})
+			statements.Debet += 100
+		}
+		type info struct {
+			Statements Statements `xml:"statements"`
+		}
+		b, err := xml.Marshal(ResponseData{Info: info{statements}, Oper: "cmt"})
+		require.NoError(t, err)
+		data, _, err := signedData([]byte("<response>" + string(b) + "</response>"))
+		require.NoError(t, err)
+		_, _ = w.Write([]byte(signedResponse(m, m.ID, string(data))))
+	}))
+}
+
+func TestClient_GetStatementsRange(t *testing.T) {
+	m := Merchant{"id", "pass"}
+	opts := StatementsOpts{
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, kievLocation),
+		EndDate:    time.Date(2021, 12, 31, 0, 0, 0, 0, kievLocation),
+		CardNumber: "1234567890123456",
+	}
+
+	for _, concurrency := range []int{0, 1, 3, 10} {
+		concurrency := concurrency
+		t.Run("Concurrency"+strconv.Itoa(concurrency), func(t *testing.T) {
+			var calls int32
+			srv := statementsServer(t, m, func(string) bool {
+				atomic.AddInt32(&calls, 1)
+				return false
+			})
+			defer srv.Close()
+			cli, err := NewClient(ClientOpts{HTTP: http.DefaultClient, BaseURL: srv.URL + "/", Merchant: m})
+			require.NoError(t, err)
+
+			statements, err := cli.GetStatementsRange(context.Background(), StatementsRangeOpts{StatementsOpts: opts, Concurrency: concurrency})
+			require.NoError(t, err)
+			require.Equal(t, int32(5), calls)
+			// a statement per day and a statement of the day before the range
+			require.Len(t, statements.Statements, 366)
+			require.Equal(t, "20201231", statements.Statements[0].Appcode)
+			for i := 1; i < len(statements.Statements); i++ {
+				require.True(t, statements.Statements[i-1].Date.Before(statements.Statements[i].Date))
+			}
+			require.Equal(t, Amount(366*100), statements.Debet)
+			require.Equal(t, "excellent", statements.Status)
+		})
+	}
+
+	t.Run("Failure", func(t *testing.T) {
+		for _, concurrency := range []int{1, 5} {
+			srv := statementsServer(t, m, func(sd string) bool { return sd == "30.06.2021" })
+			cli, err := NewClient(ClientOpts{HTTP: http.DefaultClient, BaseURL: srv.URL + "/", Merchant: m})
+			require.NoError(t, err)
+
+			statements, err := cli.GetStatementsRange(context.Background(), StatementsRangeOpts{StatementsOpts: opts, Concurrency: concurrency})
+			srv.Close()
+			require.ErrorIs(t, err, ErrHTTPStatus)
+			var rangeErr *RangeError
+			require.True(t, errors.As(err, &rangeErr))
+			require.Equal(t, 2, rangeErr.Fetched)
+			require.Equal(t, 5, rangeErr.Windows)
+			require.Equal(t, time.Date(2021, 6, 30, 0, 0, 0, 0, kievLocation), rangeErr.Window.StartDate)
+			require.EqualError(t, err, "statements window 30.06.2021-27.09.2021 failed, 2 of 5 windows fetched: unexpected http status code 500")
+			// days of fetched windows and the day before the range
+			require.Len(t, statements.Statements, 181)
+		}
+	})
+
+	t.Run("InvalidOpts", func(t *testing.T) {
+		cli := Client{}
+		_, err := cli.GetStatementsRange(context.Background(), StatementsRangeOpts{StatementsOpts: StatementsOpts{
+			StartDate: opts.EndDate, EndDate: opts.StartDate, CardNumber: opts.CardNumber,
+		}})
+		require.ErrorIs(t, err, ErrInvalidDateRange)
+		_, err = cli.GetStatementsRange(context.Background(), StatementsRangeOpts{StatementsOpts: StatementsOpts{
+			StartDate: opts.StartDate, EndDate: opts.EndDate, CardNumber: "1",
+		}})
+		require.ErrorIs(t, err, ErrBadCardNumber)
+	})
+}
+
+func Test_mergeStatements(t *testing.T) {
+	s := func(appcode string, amount Amount) Statement {
+		return Statement{Appcode: appcode, CardAmount: Funds{"UAH", amount}}
+	}
+	merged := mergeStatements([]Statements{
+		{Status: "a", Credit: 100, Debet: 20, Statements: []Statement{s("1", 100), s("2", -10), s("2", -10)}},
+		// a single "2" of the boundary day is duplicated, the other one is a new identical purchase
+		{Status: "b", Credit: 0, Debet: 30, Statements: []Statement{s("2", -10), s("2", -10), s("2", -10)}},
+		{Status: "c", Credit: 50, Debet: 0, Statements: []Statement{s("3", 50)}},
+	})
+	require.Equal(t, Statements{
+		Status:     "c",
+		Credit:     150,
+		Debet:      30,
+		Statements: []Statement{s("1", 100), s("2", -10), s("2", -10), s("2", -10), s("3", 50)},
+	}, merged)
+}
@@ -204,7 +204,7 @@ func TestClient_GetStatements(t *testing.T) {
 				return tr.Result(), nil
 			}
 
-			cli := Client{do, nil, merchant}
+			cli := Client{http: do, merchant: merchant}
 			actual, err := cli.GetStatements(context.Background(), c.opts)
 			if err != nil {
 				require.NotEmpty(t, c.errMsg)
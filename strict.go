@@ -0,0 +1,97 @@
+package p24
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultMaxResponseAge is a default max age of dates of p24 responses checked by strict verification
+const DefaultMaxResponseAge = 15 * time.Minute
+
+// Fields of p24 response checked by strict verification
+const (
+	MismatchMerchantID = "merchant id"
+	MismatchOper       = "oper"
+	MismatchCard       = "card"
+	MismatchDate       = "date"
+	MismatchFreshness  = "freshness"
+)
+
+// MismatchError reports a field of p24 response that doesn`t match the request.
+// It is returned by Client with strict verification for replayed or misrouted responses
+type MismatchError struct {
+	Field    string
+	Expected string
+	Actual   string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("response %s %q doesn`t match requested %q", e.Field, e.Actual, e.Expected)
+}
+
+func (e *MismatchError) Is(target error) bool { return target == ErrResponseMismatch }
+
+// responseCheck checks endpoint specific fields of p24 response
+type responseCheck func(resp Response) error
+
+// checkStrict cross-checks verified resp with call request if Client strict verification is enabled.
+// Merchant ids are masked in *MismatchError by Client Redaction
+func (c *Client) checkStrict(call Call, resp Response) error {
+	if !c.strict {
+		return nil
+	}
+	if resp.MerchantSign.ID != call.Req.MerchantSign.ID {
+		return &MismatchError{MismatchMerchantID, c.redact.merchantID(call.Req.MerchantSign.ID), c.redact.merchantID(resp.MerchantSign.ID)}
+	}
+	if resp.Data.Oper != call.Req.Data.Oper {
+		return &MismatchError{MismatchOper, call.Req.Data.Oper, resp.Data.Oper}
+	}
+	if call.check != nil {
+		return call.check(resp)
+	}
+	return nil
+}
+
+// checkFresh returns *MismatchError if date of response to a request sent at requested
+// is older than Client max response age, the check is disabled if the age is negative
+func (c *Client) checkFresh(requested, date time.Time, layout string) error {
+	if c.maxAge < 0 {
+		return nil
+	}
+	if oldest := requested.Add(-c.maxAge); date.Before(oldest) {
+		return &MismatchError{
+			Field:    MismatchFreshness,
+			Expected: "since " + oldest.In(kievLocation).Format(layout),
+			Actual:   date.In(kievLocation).Format(layout),
+		}
+	}
+	return nil
+}
+
+// checkCard returns *MismatchError if actual card number is not the expected one
+func checkCard(expected, actual string) error {
+	if actual != expected {
+		return &MismatchError{MismatchCard, expected, actual}
+	}
+	return nil
+}
+
+// checkStatements returns *MismatchError if statements are not of requested card and date range
+func checkStatements(opts StatementsOpts, statements Statements) error {
+	// p24 gets date range as days and returns statements of whole days in Kyiv time zone
+	start := time.Date(opts.StartDate.Year(), opts.StartDate.Month(), opts.StartDate.Day(), 0, 0, 0, 0, kievLocation)
+	end := time.Date(opts.EndDate.Year(), opts.EndDate.Month(), opts.EndDate.Day()+1, 0, 0, 0, 0, kievLocation)
+	for _, s := range statements.Statements {
+		if err := checkCard(opts.CardNumber, s.Card); err != nil {
+			return err
+		}
+		if s.Date.Before(start) || !s.Date.Before(end) {
+			return &MismatchError{
+				Field:    MismatchDate,
+				Expected: opts.StartDate.Format(statementsReqTimeLayout) + "-" + opts.EndDate.Format(statementsReqTimeLayout),
+				Actual:   s.Date.In(kievLocation).Format(statementsReqTimeLayout),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package p24
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// signedResponse returns p24 response with data signed by m on behalf of merchant id
+func signedResponse(m Merchant, id, data string) string {
+	return fmt.Sprintf(`<response version="1.0"><merchant><id>%s</id><signature>%s</signature></merchant><data>%s</data></response>`,
+		id, m.Sign([]byte(data)).Sign, data)
+}
+
+func TestClient_StrictVerify(t *testing.T) {
+	m := Merchant{"id", "pass"}
+	const card = "1234567890123456"
+	balanceAt := func(oper, card, date string) string {
+		return fmt.Sprintf(`<oper>%s</oper><info><cardbalance><card><card_number>%s</card_number></card><bal_date>%s</bal_date></cardbalance></info>`, oper, card, date)
+	}
+	balance := func(oper, card string) string { return balanceAt(oper, card, "01.01.21 10:00") }
+	now := time.Date(2021, 1, 1, 10, 5, 0, 0, kievLocation)
+	statements := func(card, date string) string {
+		return fmt.Sprintf(`<oper>cmt</oper><info><statements status="excellent" credit="0.0" debet="5.5">`+
+			`<statement card="%s" appcode="1" trandate="%s" trantime="23:59:59" amount="5.50 UAH" cardamount="-5.50 UAH" rest="10 UAH" terminal="t" description="d"/>`+
+			`</statements></info>`, card, date)
+	}
+	statementsOpts := StatementsOpts{
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, kievLocation),
+		EndDate:    time.Date(2021, 1, 2, 0, 0, 0, 0, kievLocation),
+		CardNumber: card,
+	}
+
+	cases := []struct {
+		mismatch *MismatchError
+		endpoint Endpoint
+		respBody string
+	}{
+		{endpoint: EndpointCardBalance, respBody: signedResponse(m, "id", balance("cmt", card))},
+		{
+			endpoint: EndpointCardBalance,
+			respBody: signedResponse(m, "id", balance("cmt", "6543210987654321")),
+			mismatch: &MismatchError{MismatchCard, card, "6543210987654321"},
+		},
+		{
+			endpoint: EndpointCardBalance,
+			respBody: signedResponse(m, "id", balance("other", card)),
+			mismatch: &MismatchError{MismatchOper, "cmt", "other"},
+		},
+		{endpoint: EndpointCardBalance, respBody: signedResponse(m, "id", balanceAt("cmt", card, "01.01.21 09:50"))},
+		{
+			endpoint: EndpointCardBalance,
+			respBody: signedResponse(m, "id", balanceAt("cmt", card, "01.01.21 09:49")),
+			mismatch: &MismatchError{MismatchFreshness, "since 01.01.21 09:50", "01.01.21 09:49"},
+		},
+		{endpoint: EndpointStatements, respBody: signedResponse(m, "id", statements(card, "2021-01-02"))},
+		{endpoint: EndpointStatements, respBody: signedResponse(m, "id", statements(card, "2021-01-01"))},
+		{
+			endpoint: EndpointStatements,
+			respBody: signedResponse(m, "id", statements(card, "2021-01-03")),
+			mismatch: &MismatchError{MismatchDate, "01.01.2021-02.01.2021", "03.01.2021"},
+		},
+		{
+			endpoint: EndpointStatements,
+			respBody: signedResponse(m, "id", statements(card, "2020-12-31")),
+			mismatch: &MismatchError{MismatchDate, "01.01.2021-02.01.2021", "31.12.2020"},
+		},
+		{
+			endpoint: EndpointStatements,
+			respBody: signedResponse(m, "id", statements("6543210987654321", "2021-01-01")),
+			mismatch: &MismatchError{MismatchCard, card, "6543210987654321"},
+		},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(c.respBody))
+			}))
+			defer srv.Close()
+
+			for _, strict := range []bool{false, true} {
+				cli, err := NewClient(ClientOpts{HTTP: http.DefaultClient, BaseURL: srv.URL + "/", Merchant: m, StrictVerify: strict})
+				require.NoError(t, err)
+				cli.now = func() time.Time { return now }
+
+				if c.endpoint == EndpointCardBalance {
+					_, err = cli.GetCardBalance(context.Background(), BalanceOpts{CardNumber: card})
+				} else {
+					_, err = cli.GetStatements(context.Background(), statementsOpts)
+				}
+				if !strict || c.mismatch == nil {
+					require.NoError(t, err)
+					continue
+				}
+
+				require.ErrorIs(t, err, ErrResponseMismatch)
+				var mismatch *MismatchError
+				require.True(t, errors.As(err, &mismatch))
+				require.Equal(t, c.mismatch, mismatch)
+				var p24Err *Error
+				require.True(t, errors.As(err, &p24Err))
+				require.Equal(t, ErrResponseMismatch, p24Err.Class)
+			}
+		})
+	}
+}
+
+func TestClient_checkStrict_MerchantID(t *testing.T) {
+	cli := Client{strict: true}
+	call := Call{Req: Request{MerchantSign: MerchantSign{ID: "id"}, Data: RequestData{CommonOpts: CommonOpts{Oper: "cmt"}}}}
+
+	require.NoError(t, cli.checkStrict(call, Response{
+		MerchantSign: MerchantSign{ID: "id"},
+		Data:         ResponseData{Oper: "cmt"},
+	}))
+	other := Response{
+		MerchantSign: MerchantSign{ID: "other"},
+		Data:         ResponseData{Oper: "cmt"},
+	}
+	err := cli.checkStrict(call, other)
+	require.EqualError(t, err, "response merchant id \"[REDACTED]\" doesn`t match requested \"[REDACTED]\"")
+
+	cli.redact.KeepMerchantID = true
+	err = cli.checkStrict(call, other)
+	require.EqualError(t, err, "response merchant id \"other\" doesn`t match requested \"id\"")
+}
+
+func TestClient_checkFresh(t *testing.T) {
+	requested := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	layout := cardBalanceRespDateLayout + " " + cardBalanceRespTimeLayout
+	cases := []struct {
+		maxAge time.Duration
+		date   time.Time
+		err    string
+	}{
+		{maxAge: time.Minute, date: requested},
+		{maxAge: time.Minute, date: requested.Add(-time.Minute)},
+		{maxAge: time.Minute, date: requested.Add(time.Hour)},
+		{
+			maxAge: time.Minute,
+			date:   requested.Add(-time.Minute - time.Second),
+			err:    "response freshness \"01.01.21 11:58\" doesn`t match requested \"since 01.01.21 11:59\"",
+		},
+		{maxAge: -1, date: requested.Add(-24 * time.Hour)},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			cli := Client{maxAge: c.maxAge}
+			err := cli.checkFresh(requested, c.date, layout)
+			if c.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, c.err)
+			require.ErrorIs(t, err, ErrResponseMismatch)
+		})
+	}
+}
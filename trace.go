@@ -0,0 +1,167 @@
+package p24
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Span names started by Client
+const (
+	SpanGetStatements  = "p24.GetStatements"
+	SpanGetCardBalance = "p24.GetCardBalance"
+	SpanMarshalSign    = "p24.marshal_sign"
+	SpanDoContext      = "p24.DoContext"
+	SpanAttempt        = "p24.attempt"
+	SpanRoundTrip      = "p24.round_trip"
+	SpanParseDecode    = "p24.parse_decode"
+	SpanVerify         = "p24.verify"
+)
+
+// Tracer defines minimal tracer interface.
+// Start returns ctx that carries started Span, so spans started with it become its children
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Field) (context.Context, Span)
+}
+
+// Span is a traced operation
+type Span interface {
+	SetAttributes(attrs ...Field)
+	AddEvent(name string, attrs ...Field)
+	// End finishes span with err as its status
+	End(err error)
+}
+
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, _ string, _ ...Field) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...Field)    {}
+func (nopSpan) AddEvent(string, ...Field) {}
+func (nopSpan) End(error)                 {}
+
+// NopTracer is a Tracer that does nothing
+var NopTracer Tracer = nopTracer{}
+
+// tracer returns Client Tracer or NopTracer if it is nil
+func (c *Client) tracer() Tracer {
+	if c.trace == nil {
+		return NopTracer
+	}
+	return c.trace
+}
+
+// withClientTrace returns ctx with httptrace.ClientTrace that adds http round trip events to span
+func (c *Client) withClientTrace(ctx context.Context, span Span) context.Context {
+	if c.trace == nil {
+		return ctx
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(hostPort string) { span.AddEvent("get_conn", F("host_port", hostPort)) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			span.AddEvent("got_conn", F("reused", info.Reused), F("was_idle", info.WasIdle))
+		},
+		DNSStart: func(info httptrace.DNSStartInfo) { span.AddEvent("dns_start", F("host", info.Host)) },
+		DNSDone:  func(info httptrace.DNSDoneInfo) { span.AddEvent("dns_done", F("err", info.Err)) },
+		ConnectStart: func(network, addr string) {
+			span.AddEvent("connect_start", F("network", network), F("addr", addr))
+		},
+		ConnectDone: func(network, addr string, err error) {
+			span.AddEvent("connect_done", F("network", network), F("addr", addr), F("err", err))
+		},
+		TLSHandshakeStart: func() { span.AddEvent("tls_handshake_start") },
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			span.AddEvent("tls_handshake_done", F("version", state.Version), F("err", err))
+		},
+		WroteRequest:         func(info httptrace.WroteRequestInfo) { span.AddEvent("wrote_request", F("err", info.Err)) },
+		GotFirstResponseByte: func() { span.AddEvent("got_first_response_byte") },
+	})
+}
+
+// RecordedSpan is a Span recorded by SpanRecorder
+type RecordedSpan struct {
+	Start  time.Time
+	End    time.Time
+	Err    error
+	Parent *RecordedSpan
+	Name   string
+	Attrs  []Field
+	Events []SpanEvent
+	Ended  bool
+}
+
+// SpanEvent is an event of RecordedSpan
+type SpanEvent struct {
+	Time  time.Time
+	Name  string
+	Attrs []Field
+}
+
+// SpanRecorder is an in-memory Tracer that records all started spans.
+// It is safe for concurrent use
+type SpanRecorder struct {
+	spans []*RecordedSpan
+	mu    sync.Mutex
+}
+
+type spanRecorderKey struct{}
+
+// Start implements Tracer interface for r
+func (r *SpanRecorder) Start(ctx context.Context, name string, attrs ...Field) (context.Context, Span) {
+	parent, _ := ctx.Value(spanRecorderKey{}).(*recordedSpan)
+	s := &recordedSpan{r: r, s: &RecordedSpan{
+		Name:  name,
+		Attrs: attrs,
+		Start: time.Now(),
+	}}
+	if parent != nil {
+		s.s.Parent = parent.s
+	}
+
+	r.mu.Lock()
+	r.spans = append(r.spans, s.s)
+	r.mu.Unlock()
+	return context.WithValue(ctx, spanRecorderKey{}, s), s
+}
+
+// Spans returns copies of recorded spans in the order they were started
+func (r *SpanRecorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	spans := make([]RecordedSpan, 0, len(r.spans))
+	for _, s := range r.spans {
+		spans = append(spans, *s)
+	}
+	return spans
+}
+
+type recordedSpan struct {
+	r *SpanRecorder
+	s *RecordedSpan
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...Field) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.s.Attrs = append(s.s.Attrs, attrs...)
+}
+
+func (s *recordedSpan) AddEvent(name string, attrs ...Field) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.s.Events = append(s.s.Events, SpanEvent{Time: time.Now(), Name: name, Attrs: attrs})
+}
+
+func (s *recordedSpan) End(err error) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	if !s.s.Ended {
+		s.s.End, s.s.Err, s.s.Ended = time.Now(), err, true
+	}
+}
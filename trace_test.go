@@ -0,0 +1,101 @@
+package p24
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpanRecorder(t *testing.T) {
+	r := &SpanRecorder{}
+	ctx, root := r.Start(context.Background(), "root", F("a", 1))
+	_, child := r.Start(ctx, "child")
+	child.AddEvent("event", F("b", 2))
+	child.SetAttributes(F("c", 3))
+	child.End(context.Canceled)
+	child.End(nil) // second call is noop
+	root.End(nil)
+
+	spans := r.Spans()
+	require.Len(t, spans, 2)
+	require.Equal(t, "root", spans[0].Name)
+	require.Nil(t, spans[0].Parent)
+	require.Equal(t, []Field{F("a", 1)}, spans[0].Attrs)
+	require.True(t, spans[0].Ended)
+
+	require.Equal(t, "child", spans[1].Name)
+	require.Equal(t, "root", spans[1].Parent.Name)
+	require.Equal(t, []Field{F("c", 3)}, spans[1].Attrs)
+	require.Len(t, spans[1].Events, 1)
+	require.Equal(t, "event", spans[1].Events[0].Name)
+	require.ErrorIs(t, spans[1].Err, context.Canceled)
+
+	ctx, span := NopTracer.Start(context.Background(), "nop")
+	span.AddEvent("event")
+	span.SetAttributes(F("a", 1))
+	span.End(nil)
+	require.Equal(t, context.Background(), ctx)
+}
+
+func TestClient_GetStatements_Trace(t *testing.T) {
+	respBody := `<?xml version="1.0" encoding="UTF-8"?><response version="1.0"><merchant><id>id</id><signature>68ca17bc2ca05d70ec51611dfd6a84cf1fcc388f</signature></merchant><data><oper>cmt</oper><info><statements status="excellent" credit="0.0" debet="5.5"><statement card="1234567890123456" appcode="12345" trandate="2021-01-01" trantime="05:05:05" amount="5.50 UAH" cardamount="-5.50 UAH" rest="10 UAH" terminal="PrivatBank, 123" description="test"/></statements></info></data></response>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/rest_fiz", r.URL.Path)
+		_, _ = w.Write([]byte(respBody))
+	}))
+	defer srv.Close()
+
+	r := &SpanRecorder{}
+	cli, err := NewClient(ClientOpts{
+		HTTP:     srv.Client(),
+		Merchant: Merchant{"id", "pass"},
+		BaseURL:  srv.URL,
+		Tracer:   r,
+	})
+	require.NoError(t, err)
+
+	_, err = cli.GetStatements(context.Background(), StatementsOpts{
+		StartDate:  time.Date(2021, 1, 1, 0, 0, 0, 0, kievLocation),
+		EndDate:    time.Date(2021, 1, 2, 0, 0, 0, 0, kievLocation),
+		CardNumber: "1234567890123456",
+	})
+	require.NoError(t, err)
+
+	type spanTree struct{ name, parent string }
+	var actual []spanTree
+	var roundTrip RecordedSpan
+	for _, s := range r.Spans() {
+		require.True(t, s.Ended, s.Name)
+		require.NoError(t, s.Err, s.Name)
+		parent := ""
+		if s.Parent != nil {
+			parent = s.Parent.Name
+		}
+		actual = append(actual, spanTree{s.Name, parent})
+		if s.Name == SpanRoundTrip {
+			roundTrip = s
+		}
+	}
+	require.Equal(t, []spanTree{
+		{SpanGetStatements, ""},
+		{SpanMarshalSign, SpanGetStatements},
+		{SpanDoContext, SpanGetStatements},
+		{SpanAttempt, SpanDoContext},
+		{SpanRoundTrip, SpanAttempt},
+		{SpanParseDecode, SpanAttempt},
+		{SpanVerify, SpanAttempt},
+	}, actual)
+
+	events := map[string]bool{}
+	for _, e := range roundTrip.Events {
+		events[e.Name] = true
+	}
+	require.True(t, events["got_conn"])
+	require.True(t, events["wrote_request"])
+	require.True(t, events["got_first_response_byte"])
+	require.Contains(t, roundTrip.Attrs, F("status", 200))
+}
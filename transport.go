@@ -0,0 +1,115 @@
+package p24
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrPinMismatch is a tls connection which certificate chain doesn`t match any of HTTPClientOpts pins
+var ErrPinMismatch = errors.New("certificate pin mismatch")
+
+// Default timeouts of NewHTTPClient
+const (
+	DefaultHTTPTimeout           = 60 * time.Second
+	DefaultDialTimeout           = 10 * time.Second
+	DefaultTLSHandshakeTimeout   = 10 * time.Second
+	DefaultResponseHeaderTimeout = 30 * time.Second
+	DefaultIdleConnTimeout       = 90 * time.Second
+)
+
+// HTTPClientOpts is a full set of all parameters to initialize http client by NewHTTPClient.
+// Zero durations are replaced with default ones
+type HTTPClientOpts struct {
+	// RootCAs is a set of root certificates to verify server certificates.
+	// System roots are used if nil
+	RootCAs *x509.CertPool
+
+	// Pins are base64 encoded sha256 hashes of SubjectPublicKeyInfo of certificates, see SPKIPin.
+	// A connection is rejected with ErrPinMismatch unless a certificate of its verified chain
+	// matches any of pins, so backup pins of the next keys must be listed before the key rotation.
+	// Pins are not checked if empty
+	Pins []string
+
+	// Timeout is a time limit of whole http request including reading of response body
+	Timeout time.Duration
+
+	// DialTimeout is a time limit of tcp connection establishment
+	DialTimeout time.Duration
+
+	// TLSHandshakeTimeout is a time limit of tls handshake
+	TLSHandshakeTimeout time.Duration
+
+	// ResponseHeaderTimeout is a time limit of waiting for response headers after the request is written
+	ResponseHeaderTimeout time.Duration
+}
+
+// NewHTTPClient returns http client with given opts that accepts tls 1.2+ connections only.
+// It returns an error if opts pins are invalid
+func NewHTTPClient(opts HTTPClientOpts) (*http.Client, error) {
+	pins := make(map[string]bool, len(opts.Pins))
+	for _, pin := range opts.Pins {
+		if b, err := base64.StdEncoding.DecodeString(pin); err != nil || len(b) != sha256.Size {
+			return nil, errors.Errorf("invalid pin %q: should be base64 encoded sha256 hash", pin)
+		}
+		pins[pin] = true
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    opts.RootCAs,
+	}
+	if len(pins) > 0 {
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			for _, chain := range state.VerifiedChains {
+				for _, cert := range chain {
+					if pins[SPKIPin(cert)] {
+						return nil
+					}
+				}
+			}
+			return errors.Wrapf(ErrPinMismatch, "server %s", state.ServerName)
+		}
+	}
+
+	return &http.Client{
+		Timeout: durationOrDefault(opts.Timeout, DefaultHTTPTimeout),
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   durationOrDefault(opts.DialTimeout, DefaultDialTimeout),
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSClientConfig:       tlsConfig,
+			TLSHandshakeTimeout:   durationOrDefault(opts.TLSHandshakeTimeout, DefaultTLSHandshakeTimeout),
+			ResponseHeaderTimeout: durationOrDefault(opts.ResponseHeaderTimeout, DefaultResponseHeaderTimeout),
+			IdleConnTimeout:       DefaultIdleConnTimeout,
+			ExpectContinueTimeout: time.Second,
+			MaxIdleConns:          10,
+			ForceAttemptHTTP2:     true,
+		},
+	}, nil
+}
+
+// SPKIPin returns base64 encoded sha256 hash of SubjectPublicKeyInfo of cert.
+// The pin of a server can be computed with openssl:
+//
+//	openssl s_client -connect api.privatbank.ua:443 </dev/null | openssl x509 -pubkey -noout |
+//	openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func durationOrDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
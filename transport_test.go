@@ -0,0 +1,137 @@
+package p24
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	pin := SPKIPin(srv.Certificate())
+	otherPin := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	cases := []struct {
+		opts   HTTPClientOpts
+		errMsg string
+	}{
+		{opts: HTTPClientOpts{RootCAs: roots}},
+		{opts: HTTPClientOpts{RootCAs: roots, Pins: []string{pin}}},
+		{opts: HTTPClientOpts{RootCAs: roots, Pins: []string{otherPin, pin}}},
+		{opts: HTTPClientOpts{RootCAs: roots, Pins: []string{otherPin}}, errMsg: "certificate pin mismatch"},
+		{opts: HTTPClientOpts{}, errMsg: "certificate"},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			cli, err := NewHTTPClient(c.opts)
+			require.NoError(t, err)
+
+			resp, err := cli.Get(srv.URL)
+			if c.errMsg != "" {
+				require.ErrorContains(t, err, c.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+
+	t.Run("InvalidPin", func(t *testing.T) {
+		_, err := NewHTTPClient(HTTPClientOpts{Pins: []string{"bad"}})
+		require.EqualError(t, err, `invalid pin "bad": should be base64 encoded sha256 hash`)
+	})
+}
+
+func TestNewHTTPClient_TLSVersion(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS11}
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	cli, err := NewHTTPClient(HTTPClientOpts{RootCAs: roots})
+	require.NoError(t, err)
+	_, err = cli.Get(srv.URL)
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "protocol version") || strings.Contains(err.Error(), "handshake failure"), err.Error())
+}
+
+func TestNewHTTPClient_Timeouts(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	cli, err := NewHTTPClient(HTTPClientOpts{RootCAs: roots, ResponseHeaderTimeout: 50 * time.Millisecond})
+	require.NoError(t, err)
+	require.Equal(t, DefaultHTTPTimeout, cli.Timeout)
+
+	_, err = cli.Get(srv.URL)
+	require.ErrorContains(t, err, "timeout awaiting response headers")
+}
+
+func TestNewClient_DefaultHTTP(t *testing.T) {
+	m := Merchant{"id", "pass"}
+	data := `<oper>cmt</oper><info><cardbalance><card><card_number>1234567890123456</card_number></card><bal_date>01.01.21 10:00</bal_date></cardbalance></info>`
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(signedResponse(m, "id", data)))
+	}))
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	otherPin := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	cli, err := NewClient(ClientOpts{
+		BaseURL:  srv.URL + "/",
+		Merchant: m,
+		HTTPOpts: HTTPClientOpts{RootCAs: roots, Pins: []string{otherPin, SPKIPin(srv.Certificate())}},
+	})
+	require.NoError(t, err)
+	balance, err := cli.GetCardBalance(context.Background(), BalanceOpts{CardNumber: "1234567890123456"})
+	require.NoError(t, err)
+	require.Equal(t, "1234567890123456", balance.Card.Number)
+
+	cli, err = NewClient(ClientOpts{
+		BaseURL:  srv.URL + "/",
+		Merchant: m,
+		Retry:    RetryPolicy{MaxAttempts: 3},
+		HTTPOpts: HTTPClientOpts{RootCAs: roots, Pins: []string{otherPin}},
+	})
+	require.NoError(t, err)
+	_, err = cli.GetCardBalance(context.Background(), BalanceOpts{CardNumber: "1234567890123456"})
+	require.ErrorIs(t, err, ErrPinMismatch)
+	require.ErrorIs(t, err, ErrTransport)
+	require.False(t, IsRetryable(err))
+	var p24Err *Error
+	require.True(t, errors.As(err, &p24Err))
+	require.Equal(t, 1, p24Err.Attempt)
+
+	_, err = NewClient(ClientOpts{HTTPOpts: HTTPClientOpts{Pins: []string{"bad"}}})
+	require.EqualError(t, err, `invalid client opts: invalid pin "bad": should be base64 encoded sha256 hash`)
+}